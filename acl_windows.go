@@ -0,0 +1,87 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// HardenRunKeyACL replaces the DACL of a startup location's key with a protected
+// one that only lets SYSTEM and Administrators change it. Everyone else keeps
+// read access: the current user for HKCU keys, Users for HKLM keys. An OWNER
+// RIGHTS entry limits the key's owner, normally the user for HKCU keys, to read
+// access too, so the owner cannot quietly grant itself write access again. Once
+// hardened only an elevated process can add or remove entries, or undo the
+// hardening with RestoreDefaultRunKeyACL
+func HardenRunKeyACL(registryType StartupRegistryType) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Readers of the key besides SYSTEM and Administrators
+	reader := "BU"
+	if rootKey == registry.CURRENT_USER {
+		user, err := windows.GetCurrentProcessToken().GetTokenUser()
+		if err != nil {
+			return fmt.Errorf("failed to get current user: %w", err)
+		}
+		reader = user.User.Sid.String()
+	}
+
+	// Protected DACL, inherited by subkeys: full control for SYSTEM and
+	// Administrators, read for the reader, owner rights and app containers
+	sddl := fmt.Sprintf("D:P(A;CI;KA;;;SY)(A;CI;KA;;;BA)(A;CI;KR;;;%s)(A;CI;KR;;;OW)(A;CI;KR;;;AC)", reader)
+
+	return setKeyDACL(rootKey, keyPath, sddl, windows.PROTECTED_DACL_SECURITY_INFORMATION)
+}
+
+// RestoreDefaultRunKeyACL undoes HardenRunKeyACL by removing the explicit entries
+// of the key's DACL and letting it inherit from its parent again. It usually
+// requires elevation
+func RestoreDefaultRunKeyACL(registryType StartupRegistryType) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	return setKeyDACL(rootKey, keyPath, "D:", windows.UNPROTECTED_DACL_SECURITY_INFORMATION)
+}
+
+// setKeyDACL applies the DACL of an SDDL string to a registry key
+func setKeyDACL(rootKey registry.Key, keyPath, sddl string, protection windows.SECURITY_INFORMATION) error {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return fmt.Errorf("failed to build security descriptor: %w", err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("failed to build security descriptor: %w", err)
+	}
+
+	// A NULL DACL would grant everyone full control, never write one
+	if dacl == nil {
+		return fmt.Errorf("refusing to set a NULL DACL on %s", keyPath)
+	}
+
+	k, err := registry.OpenKey(rootKey, keyPath, windows.READ_CONTROL|windows.WRITE_DAC)
+	if err != nil {
+		return fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer k.Close()
+
+	err = windows.SetSecurityInfo(windows.Handle(k), windows.SE_REGISTRY_KEY,
+		windows.DACL_SECURITY_INFORMATION|protection, nil, nil, dacl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set registry key security: %w", err)
+	}
+
+	return nil
+}
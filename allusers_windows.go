@@ -0,0 +1,83 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// serviceAccountSIDs are the hives of built-in accounts loaded under HKEY_USERS
+// that do not belong to a person: the default profile, SYSTEM, LOCAL SERVICE
+// and NETWORK SERVICE
+var serviceAccountSIDs = map[string]bool{
+	".default": true,
+	"s-1-5-18": true,
+	"s-1-5-19": true,
+	"s-1-5-20": true,
+}
+
+// ListStartupEntriesForAllUsers reads the Run and RunOnce entries of every user
+// hive loaded under HKEY_USERS, the CurrentUserRun and CurrentUserRunOnce of
+// each user. Entries are keyed by the SID of the user, SIDs that have no entries
+// are left out, as are the _Classes hives and the well-known service accounts.
+// A name present in both keys of a user keeps its Run command. Reading the hives
+// of other users usually requires elevation, users whose keys cannot be read are
+// reported in the joined error while the others are still returned. Use
+// AccountNameForSID to show the account behind a SID
+func ListStartupEntriesForAllUsers() (map[string]map[string]string, error) {
+	sids, err := loadedUserSIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	allEntries := make(map[string]map[string]string)
+	var errs []error
+	for _, sid := range sids {
+		if serviceAccountSIDs[strings.ToLower(sid)] {
+			continue
+		}
+
+		entries := make(map[string]string)
+		// RunOnce first so Run wins for a name in both
+		for _, registryType := range []StartupRegistryType{CurrentUserRunOnce, CurrentUserRun} {
+			keyPath, _ := getRegistryPath(registryType)
+			items, err := readStartupItems(registry.USERS, sid+`\`+keyPath, registryType, DefaultView)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", sid, classifyRegistryError(err, ErrKeyNotFound)))
+				continue
+			}
+			for _, item := range items {
+				entries[item.Name] = unquoteCommand(item.Command)
+			}
+		}
+
+		if len(entries) > 0 {
+			allEntries[sid] = entries
+		}
+	}
+
+	return allEntries, errors.Join(errs...)
+}
+
+// AccountNameForSID resolves a SID in string form such as S-1-5-21-...-1001 to
+// the DOMAIN\user name of its account
+func AccountNameForSID(sid string) (string, error) {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return "", fmt.Errorf("invalid SID '%s': %w", sid, err)
+	}
+
+	account, domain, _, err := s.LookupAccount("")
+	if err != nil {
+		return "", fmt.Errorf("failed to look up account of %s: %w", sid, err)
+	}
+	if domain == "" {
+		return account, nil
+	}
+	return domain + `\` + account, nil
+}
@@ -0,0 +1,68 @@
+package winstartupreg
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// StartupApproved blobs are written by Task Manager and Settings to switch an
+// entry off without deleting it. The first byte holds the state: even values
+// (0x02, 0x06) mean enabled and odd values (0x03, 0x07) mean disabled. Disabled
+// blobs carry the FILETIME of the change in bytes 4 to 11
+
+// approvalBlobEnabled reports whether a StartupApproved blob marks an entry as enabled.
+// An empty blob is treated as enabled, which is how Windows treats a missing one
+func approvalBlobEnabled(blob []byte) bool {
+	return len(blob) == 0 || blob[0]&0x01 == 0
+}
+
+// approvalBlobSize is the length of the blob Windows writes for Run entries
+const approvalBlobSize = 12
+
+// setApprovalBlobEnabled returns a blob marking an entry as enabled or disabled.
+// An existing blob keeps every byte except the low bit of the state byte, so
+// layouts of other Windows versions (longer blobs, the 0x06/0x07 states) survive
+// untouched. Without a blob a minimal 12 byte one is created, stamped with now
+// when it disables the entry
+func setApprovalBlobEnabled(blob []byte, enabled bool, now time.Time) []byte {
+	if len(blob) == 0 {
+		blob = make([]byte, approvalBlobSize)
+		blob[0] = 0x02
+		if !enabled {
+			binary.LittleEndian.PutUint64(blob[4:], toFiletime(now))
+		}
+	} else {
+		blob = append([]byte(nil), blob...)
+	}
+
+	if enabled {
+		blob[0] &^= 0x01
+	} else {
+		blob[0] |= 0x01
+	}
+
+	return blob
+}
+
+// toFiletime converts t to a Windows FILETIME, the number of 100 ns intervals since 1601
+func toFiletime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+// approvalBlobTime returns the FILETIME stored in a StartupApproved blob, reporting
+// false when the blob is too short or carries no time
+func approvalBlobTime(blob []byte) (time.Time, bool) {
+	if len(blob) < approvalBlobSize {
+		return time.Time{}, false
+	}
+	filetime := binary.LittleEndian.Uint64(blob[4:])
+	if filetime == 0 {
+		return time.Time{}, false
+	}
+	return fromFiletime(filetime), true
+}
+
+// fromFiletime converts a Windows FILETIME to a time in UTC
+func fromFiletime(filetime uint64) time.Time {
+	return time.Unix(0, int64(filetime-116444736000000000)*100).UTC()
+}
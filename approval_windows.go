@@ -0,0 +1,117 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// getApprovalPath returns the StartupApproved key that holds the enabled state
+// of entries in a startup location. Only the Run keys have one
+func getApprovalPath(registryType StartupRegistryType) (string, registry.Key, bool) {
+	switch registryType {
+	case CurrentUserRun:
+		return `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`, registry.CURRENT_USER, true
+	case AllUsersRun:
+		return `SOFTWARE\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`, registry.LOCAL_MACHINE, true
+	default:
+		return "", 0, false
+	}
+}
+
+// readApprovalBlob returns the StartupApproved blob of an entry, or nil when there is none
+func readApprovalBlob(name string, registryType StartupRegistryType) ([]byte, error) {
+	keyPath, rootKey, ok := getApprovalPath(registryType)
+	if !ok {
+		return nil, nil
+	}
+
+	return readApprovalValue(rootKey, keyPath, name)
+}
+
+// readApprovalValue reads a blob from an approval key such as StartupApproved\Run32,
+// returning nil when the key or the value is missing
+func readApprovalValue(rootKey registry.Key, keyPath, name string) ([]byte, error) {
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open approval key: %w", err)
+	}
+	defer k.Close()
+
+	blob, _, err := k.GetBinaryValue(name)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read approval value: %w", err)
+	}
+
+	return blob, nil
+}
+
+// readApprovalState reports whether an entry is enabled according to StartupApproved
+func readApprovalState(name string, registryType StartupRegistryType) (bool, error) {
+	blob, err := readApprovalBlob(name, registryType)
+	if err != nil {
+		return false, err
+	}
+
+	return approvalBlobEnabled(blob), nil
+}
+
+// writeApprovalBlob stores the StartupApproved blob of an entry, creating the key if needed
+func writeApprovalBlob(name string, registryType StartupRegistryType, blob []byte) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	keyPath, rootKey, ok := getApprovalPath(registryType)
+	if !ok {
+		return fmt.Errorf("location %s has no approval state", registryType)
+	}
+
+	k, _, err := registry.CreateKey(rootKey, keyPath, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open approval key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	if err := k.SetBinaryValue(name, blob); err != nil {
+		return fmt.Errorf("failed to set approval value: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+
+	return nil
+}
+
+// deleteApprovalBlob removes the StartupApproved blob of an entry. A missing blob is not an error
+func deleteApprovalBlob(name string, registryType StartupRegistryType) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	keyPath, rootKey, ok := getApprovalPath(registryType)
+	if !ok {
+		return nil
+	}
+
+	k, err := registry.OpenKey(rootKey, keyPath, registry.SET_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open approval key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	if err := k.DeleteValue(name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("failed to delete approval value: %w", classifyRegistryError(err, ErrEntryNotFound))
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package winstartupreg
+
+// DisablePolicy selects how Toggle.Disable switches an application off
+type DisablePolicy int
+
+const (
+	// DisableByRemoving deletes the Run value, the default
+	DisableByRemoving DisablePolicy = iota
+	// DisableByApproval keeps the Run value and marks it disabled in
+	// StartupApproved, the way Task Manager does
+	DisableByApproval
+)
+
+// Toggle is the "Start with Windows" setting of one application, backed by the
+// current user's Run key. Create it with StartupToggle
+type Toggle struct {
+	name   string
+	policy DisablePolicy
+}
+
+// ToggleOption configures StartupToggle
+type ToggleOption func(*Toggle)
+
+// WithDisablePolicy sets how Disable switches the application off
+func WithDisablePolicy(policy DisablePolicy) ToggleOption {
+	return func(t *Toggle) {
+		t.policy = policy
+	}
+}
+
+// StartupToggle returns the "Start with Windows" setting of the application
+// registered under appName, ready to back a settings checkbox
+func StartupToggle(appName string, opts ...ToggleOption) *Toggle {
+	t := &Toggle{name: appName}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// SetEnabled calls Enable or Disable
+func (t *Toggle) SetEnabled(enabled bool) error {
+	if enabled {
+		return t.Enable()
+	}
+	return t.Disable()
+}
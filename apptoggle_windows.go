@@ -0,0 +1,64 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Enabled reports whether the application starts with Windows: its Run value
+// exists and StartupApproved does not mark it disabled
+func (t *Toggle) Enabled() (bool, error) {
+	if t.name == "" {
+		return false, fmt.Errorf("entry name cannot be empty")
+	}
+
+	present, err := EntryExists(t.name, CurrentUserRun)
+	if err != nil || !present {
+		return false, err
+	}
+
+	return readApprovalState(t.name, CurrentUserRun)
+}
+
+// Enable registers the running executable under the application name and clears
+// a disabled StartupApproved state, so the application starts at the next logon
+func (t *Toggle) Enable() error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to resolve current executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		self = resolved
+	}
+
+	return AddStartupEntryEnabledAtomic(StartupEntry{Name: t.name, Command: self}, CurrentUserRun)
+}
+
+// Disable stops the application from starting with Windows according to the
+// disable policy. Disabling an application that is not registered is not an error
+func (t *Toggle) Disable() error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if t.name == "" {
+		return fmt.Errorf("entry name cannot be empty")
+	}
+
+	present, err := EntryExists(t.name, CurrentUserRun)
+	if err != nil || !present {
+		return err
+	}
+
+	if t.policy == DisableByApproval {
+		return DisableStartupEntry(t.name, CurrentUserRun)
+	}
+
+	// A stale disabled state must not outlive the entry
+	if err := RemoveStartupEntry(t.name, CurrentUserRun); err != nil {
+		return err
+	}
+	return deleteApprovalBlob(t.name, CurrentUserRun)
+}
@@ -0,0 +1,62 @@
+package winstartupreg
+
+import (
+	"debug/pe"
+	"fmt"
+)
+
+// peMachine reads the machine type from the COFF header of a PE file
+func peMachine(path string) (uint16, error) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PE header of %s: %w", path, err)
+	}
+	defer f.Close()
+
+	return f.Machine, nil
+}
+
+// checkPEImage verifies that a file is a PE image: an MZ header whose
+// e_lfanew offset leads to a PE signature and a readable COFF header
+func checkPEImage(path string) error {
+	f, err := pe.Open(path)
+	if err != nil {
+		return fmt.Errorf("not an executable: %s has no valid PE header: %w", path, err)
+	}
+	return f.Close()
+}
+
+// machineName returns the usual short name of a PE machine type
+func machineName(machine uint16) string {
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "x86"
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "x64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT:
+		return "arm"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	default:
+		return fmt.Sprintf("unknown (0x%04x)", machine)
+	}
+}
+
+// machineRunsOn reports whether an executable built for exe can run on an OS
+// whose native machine is native, either natively or through the emulation
+// Windows ships: WOW64 runs x86 on x64, and ARM64 Windows 11 emulates x86 and
+// x64. 32-bit ARM is no longer supported on ARM64 and counts as a mismatch
+func machineRunsOn(exe, native uint16) bool {
+	if exe == native {
+		return true
+	}
+
+	switch native {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return exe == pe.IMAGE_FILE_MACHINE_I386
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return exe == pe.IMAGE_FILE_MACHINE_I386 || exe == pe.IMAGE_FILE_MACHINE_AMD64
+	default:
+		return false
+	}
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"debug/pe"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// CheckArchitectureCompatibility reports whether an entry's executable can run on
+// this machine by comparing the machine type in its PE header with the native
+// architecture of the OS. Emulation is taken into account: x86 runs on x64, and
+// x86 and x64 run on ARM64 (x64 emulation needs Windows 11). Architectures are
+// named x86, x64, arm and arm64. Scripts and other non-PE files return an error
+func CheckArchitectureCompatibility(entry StartupEntry) (compatible bool, exeArch string, osArch string, err error) {
+	executable := CommandExecutable(entry.Command)
+	if _, err := os.Stat(executable); err != nil {
+		return false, "", "", fmt.Errorf("failed to resolve executable: %w", err)
+	}
+
+	exe, err := peMachine(executable)
+	if err != nil {
+		return false, "", "", err
+	}
+	native := nativeMachine()
+
+	return machineRunsOn(exe, native), machineName(exe), machineName(native), nil
+}
+
+// nativeMachine returns the PE machine type of the OS. IsWow64Process2 needs
+// Windows 10 1511, older systems can only be x86 or x64
+func nativeMachine() uint16 {
+	var processMachine, native uint16
+	if err := windows.IsWow64Process2(windows.CurrentProcess(), &processMachine, &native); err == nil {
+		return native
+	}
+
+	if is64BitWindows() {
+		return pe.IMAGE_FILE_MACHINE_AMD64
+	}
+	return pe.IMAGE_FILE_MACHINE_I386
+}
@@ -0,0 +1,36 @@
+package winstartupreg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// backupFile is the format written by ExportStartupEntries. Locations are keyed
+// by name through StartupRegistryType.MarshalText
+type backupFile struct {
+	Version int                                   `json:"version"`
+	Entries map[StartupRegistryType][]backupEntry `json:"entries"`
+}
+
+// backupEntry is one value of a backup, stored exactly as it was in the registry
+type backupEntry struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Expand  bool   `json:"expand,omitempty"` // Stored as REG_EXPAND_SZ rather than REG_SZ
+}
+
+const backupFileVersion = 1
+
+// readBackup decodes a backup written by ExportStartupEntries
+func readBackup(r io.Reader) (backupFile, error) {
+	var backup backupFile
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return backupFile{}, fmt.Errorf("failed to decode backup: %w", err)
+	}
+	if backup.Version != backupFileVersion {
+		return backupFile{}, fmt.Errorf("unsupported backup version %d", backup.Version)
+	}
+
+	return backup, nil
+}
@@ -0,0 +1,106 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// ExportStartupEntries writes every entry of the locations read by
+// ListAllStartupEntries to w as JSON, keyed by location name. Commands are kept
+// exactly as stored, together with whether they are REG_EXPAND_SZ, so that
+// ImportStartupEntries can restore them unchanged
+func ExportStartupEntries(w io.Writer) error {
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	backup := backupFile{
+		Version: backupFileVersion,
+		Entries: make(map[StartupRegistryType][]backupEntry),
+	}
+	for _, registryType := range registryTypes {
+		items, err := listStartupItems(registryType)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			backup.Entries[registryType] = append(backup.Entries[registryType], backupEntry{
+				Name:    item.Name,
+				Command: item.Command,
+				Expand:  item.ValueType == registry.EXPAND_SZ,
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(backup); err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+
+	return nil
+}
+
+// ImportStartupEntries recreates the entries of a backup written by
+// ExportStartupEntries and returns how many were written. An entry whose name
+// already exists in its location is skipped unless overwrite is set. Commands
+// are written as they were exported, without checking that the executable
+// exists. Entries that fail, for example all-users entries without elevation,
+// do not stop the import, the error lists them
+func ImportStartupEntries(r io.Reader, overwrite bool) (written int, err error) {
+	if err := checkWritable(); err != nil {
+		return 0, err
+	}
+
+	backup, err := readBackup(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for registryType, entries := range backup.Entries {
+		for _, entry := range entries {
+			if err := (StartupEntry{Name: entry.Name, Command: entry.Command}).Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s\\%s: %w", registryType, entry.Name, err))
+				continue
+			}
+
+			if !overwrite {
+				exists, err := EntryExists(entry.Name, registryType)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s\\%s: %w", registryType, entry.Name, err))
+					continue
+				}
+				if exists {
+					continue
+				}
+			}
+
+			valType := uint32(registry.SZ)
+			if entry.Expand {
+				valType = registry.EXPAND_SZ
+			}
+			if err := writeStringValue(entry.Name, entry.Command, valType, registryType); err != nil {
+				errs = append(errs, fmt.Errorf("%s\\%s: %w", registryType, entry.Name, classifyRegistryError(err, ErrKeyNotFound)))
+				continue
+			}
+			written++
+		}
+	}
+
+	if len(errs) > 0 {
+		return written, fmt.Errorf("failed to import %d startup entries: %w", len(errs), errors.Join(errs...))
+	}
+
+	return written, nil
+}
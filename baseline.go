@@ -0,0 +1,106 @@
+package winstartupreg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// baselineFile is the on-disk format of a baseline written by SaveBaseline
+type baselineFile struct {
+	Version     int      `json:"version"`
+	Fingerprint string   `json:"fingerprint"`
+	Snapshot    Snapshot `json:"snapshot"`
+}
+
+const baselineFileVersion = 1
+
+// Fingerprint returns a SHA-256 digest of the entries of the snapshot. Two
+// snapshots with the same entries have the same fingerprint, regardless of
+// when they were taken
+func (s Snapshot) Fingerprint() string {
+	var locations []StartupRegistryType
+	for location := range s.Entries {
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i] < locations[j] })
+
+	h := sha256.New()
+	for _, location := range locations {
+		entries := s.Entries[location]
+		names := make([]string, 0, len(entries))
+		for name := range entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// Length-prefix every field so no two different snapshots hash alike
+		for _, name := range names {
+			fmt.Fprintf(h, "%d:%d:%s%d:%s", location, len(name), name, len(entries[name]), entries[name])
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SaveBaseline takes a snapshot of every known location and stores it at path,
+// together with its fingerprint, for later use by CheckDrift. The file is
+// replaced atomically
+func SaveBaseline(path string, opts ...SnapshotOption) error {
+	snapshot, err := TakeSnapshot(opts...)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(baselineFile{
+		Version:     baselineFileVersion,
+		Fingerprint: snapshot.Fingerprint(),
+		Snapshot:    snapshot,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline file: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write baseline file: %w", err)
+	}
+
+	return nil
+}
+
+// CheckDrift compares the current startup entries with the baseline stored at
+// path. A current state with the baseline's fingerprint returns an empty diff
+// without comparing entry by entry. A baseline taken with drive letter
+// normalization is compared against a normalized snapshot
+func CheckDrift(path string) (SnapshotDiff, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SnapshotDiff{}, fmt.Errorf("failed to read baseline file: %w", err)
+	}
+
+	var baseline baselineFile
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return SnapshotDiff{}, fmt.Errorf("failed to decode baseline file: %w", err)
+	}
+	if baseline.Version != baselineFileVersion {
+		return SnapshotDiff{}, fmt.Errorf("unsupported baseline file version %d", baseline.Version)
+	}
+
+	var opts []SnapshotOption
+	if baseline.Snapshot.DriveLettersNormalized {
+		opts = append(opts, WithDriveLetterNormalization())
+	}
+	current, err := TakeSnapshot(opts...)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	if current.Fingerprint() == baseline.Fingerprint {
+		return SnapshotDiff{}, nil
+	}
+
+	return DiffSnapshots(baseline.Snapshot, current), nil
+}
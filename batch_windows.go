@@ -0,0 +1,103 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AddStartupEntries adds several entries to one location as a unit. Every entry
+// is validated before anything is written. When an add fails, the entries added
+// so far are rolled back in reverse order, restoring the previous command of
+// entries that already existed, and the error names the entry that failed
+func AddStartupEntries(entries []StartupEntry, registryType StartupRegistryType, opts ...AddOption) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	// Validate input
+	for _, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return fmt.Errorf("invalid startup entry '%s': %w", entry.Name, err)
+		}
+	}
+
+	// Each add remembers what it replaced, nil for entries that were new
+	var names []string
+	var replaced []*QuarantinedEntry
+
+	for _, entry := range entries {
+		previous, err := addReplacing(entry, registryType, opts...)
+		if err == nil {
+			names = append(names, entry.Name)
+			replaced = append(replaced, previous)
+			continue
+		}
+
+		// Undo the adds that succeeded, newest first
+		var rollbackErrs []error
+		for i := len(names) - 1; i >= 0; i-- {
+			var rollbackErr error
+			if previous := replaced[i]; previous != nil {
+				rollbackErr = writeStringValue(previous.Name, previous.Command, previous.ValueType, registryType)
+			} else {
+				rollbackErr = RemoveStartupEntry(names[i], registryType, WithForce())
+			}
+			if rollbackErr != nil {
+				rollbackErrs = append(rollbackErrs, rollbackErr)
+			}
+		}
+		if len(rollbackErrs) > 0 {
+			return fmt.Errorf("failed to add startup entry '%s' and to roll back: %w", entry.Name, errors.Join(append([]error{err}, rollbackErrs...)...))
+		}
+		return fmt.Errorf("failed to add startup entry '%s', rolled back: %w", entry.Name, err)
+	}
+
+	return nil
+}
+
+// addReplacing adds an entry and returns the definition it overwrote, or nil
+// when the entry did not exist before
+func addReplacing(entry StartupEntry, registryType StartupRegistryType, opts ...AddOption) (*QuarantinedEntry, error) {
+	present, err := EntryExists(entry.Name, registryType)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *QuarantinedEntry
+	if present {
+		captured, err := captureEntry(entry.Name, registryType)
+		if err != nil {
+			return nil, err
+		}
+		previous = &captured
+	}
+
+	if err := AddStartupEntry(entry, registryType, opts...); err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// RemoveStartupEntries removes several entries from one location. Entries that
+// do not exist are skipped, any other failure is collected and the remaining
+// entries are still removed. The returned error joins the failures
+func RemoveStartupEntries(names []string, registryType StartupRegistryType, opts ...RemoveOption) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		err := RemoveStartupEntry(name, registryType, opts...)
+		switch {
+		case err == nil, errors.Is(err, ErrEntryNotFound), errors.Is(err, ErrKeyNotFound):
+			// Removed, or not there to begin with
+		default:
+			errs = append(errs, fmt.Errorf("failed to remove startup entry '%s': %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// GetStartupEntryCanonical reads an entry's command both exactly as stored and in
+// canonical form. The raw form keeps embedded NUL characters and padding that
+// GetStringValue would hide, the canonical form has NULs removed and whitespace
+// collapsed. A difference between the two is a hint of deliberate obfuscation
+func GetStartupEntryCanonical(name string, registryType StartupRegistryType) (raw, canonical string, err error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key with read access
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	// Read the raw value bytes
+	n, valType, err := k.GetValue(name, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read startup entry '%s' in %s: %w", name, registryType, classifyRegistryError(err, ErrEntryNotFound))
+	}
+	if valType != registry.SZ && valType != registry.EXPAND_SZ {
+		return "", "", fmt.Errorf("startup entry '%s' is not a string value", name)
+	}
+	buf := make([]byte, n)
+	if n, _, err = k.GetValue(name, buf); err != nil {
+		return "", "", fmt.Errorf("failed to read startup entry '%s' in %s: %w", name, registryType, err)
+	}
+
+	raw = decodeRawString(buf[:n])
+	return raw, canonicalCommand(raw), nil
+}
+
+// decodeRawString decodes UTF-16LE registry data without stopping at embedded
+// NULs. Only the single terminating NUL of a well-formed string is dropped
+func decodeRawString(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	if len(units) > 0 && units[len(units)-1] == 0 {
+		units = units[:len(units)-1]
+	}
+
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,70 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// CompareAndSwap writes newCommand to an entry only if its current command equals
+// oldCommand, or if the entry is absent when oldCommand is empty. The read and the
+// write go through a single open key handle to keep the window for races small.
+// A mismatch is reported as swapped=false without an error so callers can re-read
+// and retry. The command is stored verbatim, keeping an existing REG_EXPAND_SZ type
+func CompareAndSwap(name, oldCommand, newCommand string, registryType StartupRegistryType) (swapped bool, err error) {
+	if err := checkWritable(); err != nil {
+		return false, err
+	}
+	if err := requireElevation(registryType); err != nil {
+		return false, err
+	}
+
+	// Validate input
+	if name == "" {
+		return false, fmt.Errorf("entry name cannot be empty")
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key once for both the read and the write
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE|registry.SET_VALUE)
+	if err != nil {
+		return false, fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	// Open the journal before anything changes so a required journal can stop the swap
+	journal, err := openJournal()
+	if err != nil {
+		return false, err
+	}
+	defer journal.Close()
+
+	current, valType, err := k.GetStringValue(name)
+	switch {
+	case errors.Is(err, registry.ErrNotExist):
+		if oldCommand != "" {
+			return false, nil
+		}
+		valType = registry.SZ
+	case err != nil:
+		return false, fmt.Errorf("failed to read registry value: %w", classifyRegistryError(err, ErrEntryNotFound))
+	case oldCommand == "" || current != oldCommand:
+		return false, nil
+	}
+
+	if valType == registry.EXPAND_SZ {
+		err = k.SetExpandStringValue(name, newCommand)
+	} else {
+		err = k.SetStringValue(name, newCommand)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to set registry value: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+
+	return true, journal.record(JournalEntry{Operation: JournalAdd, Location: registryType, Name: name, Before: current, After: newCommand})
+}
@@ -0,0 +1,11 @@
+//go:build windows && !386
+
+package winstartupreg
+
+import "unsafe"
+
+// variantArgs returns the call arguments for passing v by value. On 64-bit
+// Windows a VARIANT is larger than a register, so the ABI passes a pointer to it
+func variantArgs(v *variant) []uintptr {
+	return []uintptr{uintptr(unsafe.Pointer(v))}
+}
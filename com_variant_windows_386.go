@@ -0,0 +1,12 @@
+//go:build windows && 386
+
+package winstartupreg
+
+import "unsafe"
+
+// variantArgs returns the call arguments for passing v by value. On 32-bit
+// Windows the whole 16 byte VARIANT is pushed onto the stack
+func variantArgs(v *variant) []uintptr {
+	words := (*[4]uintptr)(unsafe.Pointer(v))
+	return words[:]
+}
@@ -0,0 +1,146 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modole32    = windows.NewLazySystemDLL("ole32.dll")
+	modoleaut32 = windows.NewLazySystemDLL("oleaut32.dll")
+
+	procCoCreateInstance = modole32.NewProc("CoCreateInstance")
+	procSysAllocString   = modoleaut32.NewProc("SysAllocString")
+	procSysFreeString    = modoleaut32.NewProc("SysFreeString")
+)
+
+const (
+	clsctxInprocServer = 0x1
+
+	// rpcEChangedMode is returned by CoInitializeEx when the thread already
+	// joined a different apartment. COM is still usable in that case
+	rpcEChangedMode = syscall.Errno(0x80010106)
+
+	vtEmpty = 0
+	vtI4    = 3
+)
+
+// comObject is the memory layout shared by every COM interface pointer: a
+// pointer to the vtable. Methods are called by their vtable index, counting
+// the three IUnknown methods (and the four IDispatch methods where present)
+type comObject struct {
+	vtbl *[64]uintptr
+}
+
+// call invokes the vtable method at index with the object as the implicit
+// first argument and converts a failing HRESULT into an error
+func (o *comObject) call(index int, args ...uintptr) error {
+	callArgs := append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)
+	hr, _, _ := syscall.SyscallN(o.vtbl[index], callArgs...)
+	if int32(hr) < 0 {
+		return windows.Errno(hr)
+	}
+	return nil
+}
+
+// Release decrements the reference count of the object (IUnknown::Release)
+func (o *comObject) Release() {
+	if o != nil {
+		_ = o.call(2)
+	}
+}
+
+// QueryInterface asks the object for another interface (IUnknown::QueryInterface)
+func (o *comObject) QueryInterface(iid *windows.GUID) (*comObject, error) {
+	var out *comObject
+	if err := o.call(0, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out))); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// variant mirrors the OLE VARIANT structure. The union is two pointers wide,
+// which gives the 16 byte layout on 32-bit and the 24 byte layout on 64-bit
+type variant struct {
+	vt        uint16
+	reserved1 uint16
+	reserved2 uint16
+	reserved3 uint16
+	val       [2]uintptr
+}
+
+// newInt32Variant builds a VT_I4 variant
+func newInt32Variant(i int32) variant {
+	v := variant{vt: vtI4}
+	v.val[0] = uintptr(uint32(i))
+	return v
+}
+
+// withCOM runs fn on a locked OS thread that has been initialised for COM
+func withCOM(fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	err := windows.CoInitializeEx(0, windows.COINIT_APARTMENTTHREADED)
+	switch {
+	case err == nil, errors.Is(err, syscall.Errno(1)): // S_OK, S_FALSE
+		defer windows.CoUninitialize()
+	case errors.Is(err, rpcEChangedMode):
+		// Already initialised by someone else in another mode, nothing to undo
+	default:
+		return err
+	}
+
+	return fn()
+}
+
+// coCreateInstance creates an in-process COM object and returns the requested interface
+func coCreateInstance(clsid, iid *windows.GUID) (*comObject, error) {
+	var out *comObject
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(clsid)),
+		0,
+		clsctxInprocServer,
+		uintptr(unsafe.Pointer(iid)),
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if int32(hr) < 0 {
+		return nil, windows.Errno(hr)
+	}
+	return out, nil
+}
+
+// bstr is an OLE string allocated with SysAllocString
+type bstr *uint16
+
+// allocBSTR allocates a BSTR copy of s. It must be released with freeBSTR
+func allocBSTR(s string) (bstr, error) {
+	p, err := windows.UTF16PtrFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	r, _, _ := procSysAllocString.Call(uintptr(unsafe.Pointer(p)))
+	if r == 0 {
+		return nil, windows.ERROR_OUTOFMEMORY
+	}
+	return *(*bstr)(unsafe.Pointer(&r)), nil
+}
+
+// freeBSTR releases a BSTR, nil is allowed
+func freeBSTR(b bstr) {
+	if b != nil {
+		procSysFreeString.Call(uintptr(unsafe.Pointer(b)))
+	}
+}
+
+// takeBSTR converts a BSTR returned by a COM method to a Go string and frees it
+func takeBSTR(b bstr) string {
+	defer freeBSTR(b)
+	return windows.UTF16PtrToString(b)
+}
@@ -0,0 +1,484 @@
+package winstartupreg
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ParseCommand splits a startup command line into its executable and arguments,
+// following the rules Windows uses for command lines: a quoted executable ends at
+// the closing quote, an unquoted one at the first whitespace, and arguments honour
+// double quotes and backslash escaping
+//
+// Two common corruptions are tolerated: an executable wrapped in doubled quotes
+// (""C:\app.exe"") is read as if it were quoted once, and trailing spaces and
+// semicolons are ignored
+//
+// A command of the form cmd /c start ["title"] [switches] target is unwrapped and
+// the target and its arguments are returned, since that is what actually runs
+func ParseCommand(command string) (executable string, args []string) {
+	command = trimCommandGarbage(command)
+	if _, target, ok := unwrapCmdStart(command); ok {
+		command = target
+	}
+
+	executable, rest, _ := splitCommand(command)
+	return executable, splitArgs(rest)
+}
+
+// unwrapCmdStart recognises the cmd /c start idiom and returns the command line
+// that start launches. The prefix is the wrapper text in front of the target,
+// with an empty "" title added when it had none, so that any target, quoted or
+// not, can be appended to it. A quoted string directly after start is its window
+// title rather than the target, and ok is false when nothing follows the title
+func unwrapCmdStart(command string) (prefix, target string, ok bool) {
+	executable, rest, _ := splitCommand(command)
+	base := strings.ToLower(windowsBase(executable))
+	if base != "cmd" && base != "cmd.exe" {
+		return "", "", false
+	}
+
+	// Skip the switches of cmd itself up to /c or /k
+	var token string
+	for {
+		token, rest = nextRawToken(rest)
+		if token == "" || !strings.HasPrefix(token, "/") {
+			return "", "", false
+		}
+		if strings.EqualFold(token, "/c") || strings.EqualFold(token, "/k") {
+			break
+		}
+	}
+
+	if token, rest = nextRawToken(rest); !strings.EqualFold(token, "start") {
+		return "", "", false
+	}
+
+	titled := false
+	for {
+		remaining := strings.TrimLeft(rest, " \t")
+		token, rest = nextRawToken(remaining)
+
+		switch {
+		case token == "":
+			return "", "", false
+		case strings.HasPrefix(token, `"`) && !titled:
+			titled = true
+		case strings.HasPrefix(token, "/"):
+			// /D takes the start directory as a separate argument
+			if strings.EqualFold(token, "/d") {
+				_, rest = nextRawToken(rest)
+			}
+		default:
+			prefix = command[:len(command)-len(remaining)]
+			if !titled {
+				prefix += `"" `
+			}
+			return prefix, remaining, true
+		}
+	}
+}
+
+// windowsBase returns the last element of a path using both Windows separators,
+// whatever the OS the package is built for
+func windowsBase(path string) string {
+	return path[strings.LastIndexAny(path, `\/`)+1:]
+}
+
+// nextRawToken returns the next whitespace-separated token of s with its quotes
+// kept, together with the text that follows it
+func nextRawToken(s string) (token, rest string) {
+	s = strings.TrimLeft(s, " \t")
+
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case (s[i] == ' ' || s[i] == '\t') && !inQuotes:
+			return s[:i], s[i:]
+		}
+	}
+
+	return s, ""
+}
+
+// trimCommandGarbage strips the trailing whitespace and semicolons that corrupted
+// Run values sometimes carry
+func trimCommandGarbage(command string) string {
+	return strings.TrimRight(command, " \t;")
+}
+
+// splitCommand separates the executable of a command line from the raw, unparsed
+// argument text that follows it and reports whether the executable was quoted
+func splitCommand(command string) (executable, rest string, quoted bool) {
+	command = strings.TrimLeft(command, " \t")
+
+	if strings.HasPrefix(command, `"`) {
+		// A run of quotes directly before the path, as in ""C:\app.exe"", counts as
+		// a single one and so does the same number of quotes closing it
+		quotes := len(command) - len(strings.TrimLeft(command, `"`))
+		if quotes == len(command) || strings.IndexByte(" \t", command[quotes]) >= 0 {
+			quotes = 1
+		}
+		command = command[quotes:]
+
+		// The executable runs up to the closing quote, escapes do not apply here
+		end := strings.IndexByte(command, '"')
+		if end < 0 {
+			return command, "", true
+		}
+		rest = command[end:]
+		for i := 0; i < quotes && strings.HasPrefix(rest, `"`); i++ {
+			rest = rest[1:]
+		}
+		return command[:end], rest, true
+	}
+
+	end := strings.IndexAny(command, " \t")
+	if end < 0 {
+		return command, "", false
+	}
+	return command[:end], command[end:], false
+}
+
+// splitProbedCommand is splitCommand for a command whose unquoted executable
+// path may contain spaces. Like CreateProcess it tries every prefix ending at
+// whitespace, shortest first, and takes the first that names an existing file,
+// expanding variables through lookup only for the check. When none exists, as
+// for an executable that has moved, the first prefix ending in .exe is taken
+func splitProbedCommand(command string, lookup func(string) (string, bool)) (executable, rest string, quoted bool) {
+	executable, rest, quoted = splitCommand(command)
+	command = strings.TrimLeft(command, " \t")
+	if quoted || !strings.ContainsAny(command, " \t") {
+		return executable, rest, quoted
+	}
+
+	fallback := -1
+	for i := 0; i <= len(command); i++ {
+		if i < len(command) && command[i] != ' ' && command[i] != '\t' {
+			continue
+		}
+
+		candidate := expandVariables(command[:i], lookup)
+		if isRegularFile(candidate) || (filepath.Ext(candidate) == "" && isRegularFile(candidate+".exe")) {
+			return command[:i], command[i:], false
+		}
+		if fallback < 0 && strings.EqualFold(filepath.Ext(candidate), ".exe") {
+			fallback = i
+		}
+	}
+	if fallback >= 0 {
+		return command[:fallback], command[fallback:], false
+	}
+
+	return executable, rest, quoted
+}
+
+// composeCommand joins an executable and raw argument text into a command line,
+// quoting the executable when it contains whitespace
+func composeCommand(executable, rest string) string {
+	if strings.ContainsAny(executable, " \t") {
+		executable = `"` + executable + `"`
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		return executable + " " + rest
+	}
+	return executable
+}
+
+// joinArgs quotes each argument so that splitArgs, and CommandLineToArgvW, read
+// it back unchanged and joins them with spaces
+func joinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg quotes a single argument when it is empty or contains whitespace or
+// quotes. Inside the quotes, backslashes are doubled only where they precede a
+// quote, which is the only place CommandLineToArgvW treats them as escapes
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := 0; i < len(arg); i++ {
+		switch arg[i] {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteByte(arg[i])
+	}
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// entryCommandLine composes the stored value of an entry from its executable and
+// arguments. The executable is quoted when it, or its expanded form, contains
+// whitespace, otherwise Windows would stop reading the path at the first space
+func entryCommandLine(executable, expanded string, args []string) string {
+	if strings.ContainsAny(expanded, " \t") && !strings.ContainsAny(executable, " \t") {
+		executable = `"` + executable + `"`
+	}
+	return composeCommand(executable, joinArgs(args))
+}
+
+// unquoteCommand removes the quotes around a command that is nothing but a single
+// quoted path, the form entryCommandLine stores an executable with spaces in
+func unquoteCommand(command string) string {
+	if len(command) >= 2 && command[0] == '"' && command[len(command)-1] == '"' &&
+		!strings.Contains(command[1:len(command)-1], `"`) {
+		return command[1 : len(command)-1]
+	}
+	return command
+}
+
+// ParseStartupEntry splits a stored startup value into the Command and Args that
+// AddStartupEntry would store it from. A quoted executable ends at the closing
+// quote; an unquoted one at the first whitespace, unless the whole value names an
+// existing file. Unlike ParseCommand no wrapper such as cmd /c start is unwrapped
+func ParseStartupEntry(name, value string) StartupEntry {
+	executable, rest, quoted := splitCommand(value)
+	if !quoted && rest != "" && isRegularFile(strings.TrimSpace(value)) {
+		return StartupEntry{Name: name, Command: strings.TrimSpace(value)}
+	}
+	return StartupEntry{Name: name, Command: executable, Args: splitArgs(rest)}
+}
+
+// CommandExecutable returns the executable a startup command launches, with
+// environment variables expanded. An unquoted path containing spaces is resolved
+// the way CreateProcess does, by trying each space-separated prefix in turn, and
+// a bare file name is looked up on the PATH. The target of cmd /c start is
+// returned rather than cmd itself
+func CommandExecutable(command string) string {
+	return commandExecutable(command, os.LookupEnv)
+}
+
+// commandExecutable is CommandExecutable with the variables resolved through lookup
+func commandExecutable(command string, lookup func(string) (string, bool)) string {
+	expanded := expandVariables(strings.TrimSpace(command), lookup)
+	if _, target, ok := unwrapCmdStart(trimCommandGarbage(expanded)); ok {
+		expanded = target
+	}
+	executable, _ := ParseCommand(expanded)
+
+	if !strings.HasPrefix(expanded, `"`) && strings.ContainsAny(expanded, " \t") {
+		for i := 0; i <= len(expanded); i++ {
+			if i < len(expanded) && expanded[i] != ' ' && expanded[i] != '\t' {
+				continue
+			}
+
+			candidate := expanded[:i]
+			if isRegularFile(candidate) {
+				return candidate
+			}
+			if filepath.Ext(candidate) == "" && isRegularFile(candidate+".exe") {
+				return candidate + ".exe"
+			}
+		}
+	}
+
+	if executable != "" && !strings.ContainsAny(executable, `\/:`) {
+		if path, err := exec.LookPath(executable); err == nil {
+			return path
+		}
+	}
+
+	return executable
+}
+
+// scriptExtensions are the file types Windows runs through a script host or
+// cmd.exe rather than loading them as a PE image
+var scriptExtensions = map[string]bool{
+	".bat": true,
+	".cmd": true,
+	".ps1": true,
+	".vbs": true,
+}
+
+// scriptInterpreters are the programs that take a script as an argument
+var scriptInterpreters = map[string]bool{
+	"cmd":        true,
+	"cscript":    true,
+	"powershell": true,
+	"pwsh":       true,
+	"wscript":    true,
+}
+
+// CommandScript returns the script a startup command launches, or "" when it
+// launches an executable. Both a script run directly through its file
+// association and one passed to an interpreter, as in
+// powershell -File C:\Tools\start.ps1, are recognised. Scripts are the .bat,
+// .cmd, .ps1 and .vbs files, and environment variables are expanded
+func CommandScript(command string) string {
+	return commandScript(command, os.LookupEnv)
+}
+
+// commandScript is CommandScript with the variables resolved through lookup
+func commandScript(command string, lookup func(string) (string, bool)) string {
+	executable := commandExecutable(command, lookup)
+	if isScriptPath(executable) {
+		return executable
+	}
+
+	interpreter := strings.TrimSuffix(strings.ToLower(windowsBase(executable)), ".exe")
+	if !scriptInterpreters[interpreter] {
+		return ""
+	}
+
+	_, args := ParseCommand(expandVariables(strings.TrimSpace(command), lookup))
+	for _, arg := range args {
+		if isScriptPath(arg) {
+			return arg
+		}
+	}
+
+	return ""
+}
+
+// isScriptPath reports whether a path names one of the scriptExtensions
+func isScriptPath(path string) bool {
+	return scriptExtensions[strings.ToLower(filepath.Ext(windowsBase(path)))]
+}
+
+// splitArgs splits an argument string using the CommandLineToArgvW rules: 2n
+// backslashes before a quote become n backslashes and toggle quoting, 2n+1 become
+// n backslashes and a literal quote, and "" inside quotes is a literal quote
+func splitArgs(s string) []string {
+	var (
+		args     []string
+		current  strings.Builder
+		inQuotes bool
+		inArg    bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '\\':
+			n := 0
+			for i < len(s) && s[i] == '\\' {
+				n++
+				i++
+			}
+			if i < len(s) && s[i] == '"' {
+				current.WriteString(strings.Repeat(`\`, n/2))
+				if n%2 == 1 {
+					current.WriteByte('"')
+				} else {
+					inQuotes = !inQuotes
+				}
+			} else {
+				current.WriteString(strings.Repeat(`\`, n))
+				i--
+			}
+			inArg = true
+		case c == '"':
+			if inQuotes && i+1 < len(s) && s[i+1] == '"' {
+				current.WriteByte('"')
+				i++
+			} else {
+				inQuotes = !inQuotes
+			}
+			inArg = true
+		case (c == ' ' || c == '\t') && !inQuotes:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+// expandVariables replaces %NAME% references the way ExpandEnvironmentStrings
+// does: a reference to an undefined variable, or a lone %, is kept literally
+func expandVariables(s string, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+
+	for {
+		start := strings.IndexByte(s, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		name := s[start+1 : end]
+		if value, ok := lookup(name); ok && name != "" {
+			b.WriteString(s[:start])
+			b.WriteString(value)
+			s = s[end+1:]
+			continue
+		}
+
+		// Not a variable, keep the first % and rescan from the second one
+		b.WriteString(s[:end])
+		s = s[end:]
+	}
+
+	b.WriteString(s)
+	return b.String()
+}
+
+// isRegularFile reports whether path names an existing file that is not a directory
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// canonicalCommand strips NUL characters, collapses every run of whitespace
+// (including unusual Unicode spaces) into a single space and trims the ends
+func canonicalCommand(raw string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(raw, "\x00", "")), " ")
+}
+
+// resolveCommand normalizes a command to an absolute path and checks that the
+// executable exists and is not a directory
+func resolveCommand(command string) (string, error) {
+	fullPath, err := filepath.Abs(command)
+	if err != nil {
+		return "", fmt.Errorf("invalid command path: %w", err)
+	}
+
+	// Check if the executable exists
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("executable does not exist: %s", fullPath)
+	}
+	if err == nil && info.IsDir() {
+		return "", fmt.Errorf("not an executable: %s is a directory", fullPath)
+	}
+
+	return fullPath, nil
+}
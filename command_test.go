@@ -0,0 +1,96 @@
+package winstartupreg_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+var _ = Describe("Parsing Startup Commands", func() {
+	DescribeTable("Splitting the executable from its arguments",
+		func(command, executable string, args []string) {
+			exe, parsedArgs := winstartupreg.ParseCommand(command)
+			Expect(exe).To(Equal(executable))
+			if len(args) == 0 {
+				Expect(parsedArgs).To(BeEmpty())
+			} else {
+				Expect(parsedArgs).To(Equal(args))
+			}
+		},
+		Entry("bare executable", `C:\Tools\app.exe`, `C:\Tools\app.exe`, nil),
+		Entry("unquoted with arguments", `C:\Tools\app.exe --tray -v`, `C:\Tools\app.exe`, []string{"--tray", "-v"}),
+		Entry("quoted path with spaces", `"C:\Program Files\App\app.exe" --tray`, `C:\Program Files\App\app.exe`, []string{"--tray"}),
+		Entry("quoted argument", `app.exe "C:\My Data\cfg.ini"`, `app.exe`, []string{`C:\My Data\cfg.ini`}),
+		Entry("escaped quote", `app.exe say\"hi\"`, `app.exe`, []string{`say"hi"`}),
+		Entry("trailing backslashes before quote", `app.exe "C:\dir\\"`, `app.exe`, []string{`C:\dir\`}),
+		Entry("doubled quotes around the executable", `""C:\Program Files\App\app.exe"" --tray`, `C:\Program Files\App\app.exe`, []string{"--tray"}),
+		Entry("trailing spaces and semicolons", `C:\Tools\app.exe --tray ; `, `C:\Tools\app.exe`, []string{"--tray"}),
+		Entry("empty quoted argument", `"C:\Tools\app.exe" ""`, `C:\Tools\app.exe`, []string{""}),
+		Entry("cmd start with an empty title", `cmd /c start "" "C:\Program Files\App\app.exe" --flag`, `C:\Program Files\App\app.exe`, []string{"--flag"}),
+		Entry("cmd start with a title and switches", `C:\Windows\System32\cmd.exe /C START "My App" /min C:\Tools\app.exe -v`, `C:\Tools\app.exe`, []string{"-v"}),
+		Entry("cmd start without a title", `cmd.exe /q /c start /d C:\Tools app.exe`, `app.exe`, nil),
+		Entry("cmd start with only a title", `cmd /c start "C:\Tools\app.exe"`, `cmd`, []string{"/c", "start", `C:\Tools\app.exe`}),
+		Entry("cmd without start", `cmd /c C:\Tools\script.bat`, `cmd`, []string{"/c", `C:\Tools\script.bat`}),
+	)
+
+	It("Should return an unresolvable executable unchanged", func() {
+		Expect(winstartupreg.CommandExecutable(`"C:\Missing\app.exe" --tray`)).To(Equal(`C:\Missing\app.exe`))
+	})
+
+	DescribeTable("Recognising script launches",
+		func(command, script string) {
+			Expect(winstartupreg.CommandScript(command)).To(Equal(script))
+		},
+		Entry("batch file run directly", `"C:\Tools\start up.bat" --quiet`, `C:\Tools\start up.bat`),
+		Entry("upper-case extension", `C:\Tools\START.CMD`, `C:\Tools\START.CMD`),
+		Entry("PowerShell script", `powershell.exe -NoProfile -File "C:\Tools\start.ps1"`, `C:\Tools\start.ps1`),
+		Entry("VBScript through wscript", `C:\Windows\System32\wscript.exe //B C:\Tools\hidden.vbs`, `C:\Tools\hidden.vbs`),
+		Entry("batch file through cmd", `cmd /c C:\Tools\sync.bat`, `C:\Tools\sync.bat`),
+		Entry("batch file through cmd start", `cmd /c start "" "C:\Tools\sync.bat"`, `C:\Tools\sync.bat`),
+		Entry("executable taking a script argument", `C:\Tools\app.exe C:\Tools\config.bat`, ``),
+		Entry("plain executable", `"C:\Program Files\App\app.exe" --tray`, ``),
+	)
+
+	DescribeTable("Splitting a stored value into an entry",
+		func(value, command string, args []string) {
+			entry := winstartupreg.ParseStartupEntry("App", value)
+			Expect(entry.Name).To(Equal("App"))
+			Expect(entry.Command).To(Equal(command))
+			Expect(entry.Args).To(Equal(args))
+		},
+		Entry("bare executable", `C:\Tools\app.exe`, `C:\Tools\app.exe`, nil),
+		Entry("quoted executable with arguments", `"C:\Program Files\App\app.exe" --minimized --tray`, `C:\Program Files\App\app.exe`, []string{"--minimized", "--tray"}),
+		Entry("quoted arguments", `C:\Tools\app.exe "C:\My Data\\" say\"hi\" ""`, `C:\Tools\app.exe`, []string{`C:\My Data\`, `say"hi"`, ""}),
+		Entry("cmd start is kept as is", `cmd /c start "" app.exe`, `cmd`, []string{"/c", "start", "", "app.exe"}),
+	)
+
+	It("Should reject arguments containing NUL characters", func() {
+		entry := winstartupreg.StartupEntry{Name: "App", Command: `C:\Tools\app.exe`, Args: []string{"--tray\x00"}}
+		Expect(entry.Validate()).To(MatchError(ContainSubstring("NUL")))
+	})
+
+	It("Should find an unquoted executable path with spaces by probing for the file", func() {
+		executable := filepath.Join(GinkgoT().TempDir(), "Program Files", "App", "app.exe")
+		Expect(os.MkdirAll(filepath.Dir(executable), 0o755)).To(Succeed())
+		Expect(os.WriteFile(executable, []byte{0x4D, 0x5A}, 0o644)).To(Succeed())
+
+		exe, rest, quoted := winstartupreg.SplitProbedCommand(executable+" --tray", os.LookupEnv)
+		Expect(exe).To(Equal(executable))
+		Expect(rest).To(Equal(" --tray"))
+		Expect(quoted).To(BeFalse())
+	})
+
+	It("Should fall back to the first .exe prefix when a spaced executable is missing", func() {
+		exe, rest, _ := winstartupreg.SplitProbedCommand(`C:\Missing Dir\app.exe --tray`, os.LookupEnv)
+		Expect(exe).To(Equal(`C:\Missing Dir\app.exe`))
+		Expect(rest).To(Equal(" --tray"))
+	})
+
+	It("Should resolve the target of cmd /c start rather than cmd", func() {
+		Expect(winstartupreg.CommandExecutable(`cmd /c start "" "C:\Missing\app.exe" --tray`)).To(Equal(`C:\Missing\app.exe`))
+	})
+})
@@ -0,0 +1,20 @@
+package winstartupreg
+
+// StartupDiagnostic describes the health of a single startup entry
+type StartupDiagnostic struct {
+	Location   StartupRegistryType // Registry location the entry was read from
+	Name       string              // Value name of the entry
+	Command    string              // Stored command
+	Executable string              // Executable the command resolves to
+	Script     string              // Script the command launches, empty for an executable
+	Enabled    bool                // Not disabled through StartupApproved
+	Exists     bool                // The executable, and the script if any, is present on disk
+	Signed     bool                // The executable has a valid embedded signature
+}
+
+// Problem reports whether the entry needs attention: its executable or script is
+// missing, or its executable is unsigned. Scripts carry no embedded signature, so
+// a script launch whose files are present is healthy
+func (d StartupDiagnostic) Problem() bool {
+	return !d.Exists || (d.Script == "" && !d.Signed)
+}
@@ -0,0 +1,64 @@
+//go:build windows
+
+package winstartupreg
+
+import "sort"
+
+// DiagnoseStartupEntries inspects every entry in the known locations and reports
+// its enabled state, whether its executable exists and whether it is signed.
+// Results are ordered by location and then by name
+func DiagnoseStartupEntries() ([]StartupDiagnostic, error) {
+	allEntries, err := ListAllStartupEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []StartupDiagnostic
+	for registryType, entries := range allEntries {
+		for name, command := range entries {
+			diagnostics = append(diagnostics, diagnoseEntry(name, command, registryType))
+		}
+	}
+
+	sort.Slice(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Location != diagnostics[j].Location {
+			return diagnostics[i].Location < diagnostics[j].Location
+		}
+		return diagnostics[i].Name < diagnostics[j].Name
+	})
+
+	return diagnostics, nil
+}
+
+// diagnoseEntry computes the diagnostic of one entry. An unreadable approval
+// state is reported as enabled since that is what Windows assumes. The signature
+// is only checked for executables, a script launched directly is never Signed
+func diagnoseEntry(name, command string, registryType StartupRegistryType) StartupDiagnostic {
+	executable := CommandExecutable(command)
+	script := CommandScript(command)
+
+	diagnostic := StartupDiagnostic{
+		Location:   registryType,
+		Name:       name,
+		Command:    command,
+		Executable: executable,
+		Script:     script,
+		Enabled:    true,
+		Exists:     isRegularFile(executable),
+	}
+
+	if enabled, err := readApprovalState(name, registryType); err == nil {
+		diagnostic.Enabled = enabled
+	}
+
+	// A script run by an interpreter needs both the interpreter and the script,
+	// a script run through its file association is the executable itself
+	if script != "" && script != executable {
+		diagnostic.Exists = diagnostic.Exists && isRegularFile(script)
+	}
+	if diagnostic.Exists && script != executable {
+		diagnostic.Signed = verifySignature(executable) == nil
+	}
+
+	return diagnostic
+}
@@ -0,0 +1,108 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// explorerPolicyPath holds the "Do not process the run list" policies in both hives
+const explorerPolicyPath = `Software\Microsoft\Windows\CurrentVersion\Policies\Explorer`
+
+// runListPolicies maps each location to the policy value that switches it off
+var runListPolicies = map[StartupRegistryType]string{
+	CurrentUserRun:     "DisableCurrentUserRun",
+	CurrentUserRunOnce: "DisableCurrentUserRunOnce",
+	AllUsersRun:        "DisableLocalMachineRun",
+	AllUsersRunOnce:    "DisableLocalMachineRunOnce",
+}
+
+// ListEffectiveStartupEntries retrieves the entries of every known location,
+// including the policy Run keys, and reports for each whether it will actually
+// run at the current user's next logon. An entry does not run when its run list
+// is disabled by policy, when it is disabled through StartupApproved, or when its
+// executable is missing or cannot be read by the current user
+func ListEffectiveStartupEntries() ([]EffectiveEntry, error) {
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+		CurrentUserPolicyRun,
+		AllUsersPolicyRun,
+	}
+
+	var effective []EffectiveEntry
+	for _, registryType := range registryTypes {
+		items, err := listStartupItems(registryType)
+		if err != nil {
+			return nil, err
+		}
+
+		policyDisabled := runListDisabledByPolicy(registryType)
+		for _, item := range items {
+			item.Enforced = isNumberedLocation(registryType)
+			entry := EffectiveEntry{StartupItem: item}
+			entry.Reason = notRunningReason(item, policyDisabled)
+			entry.WillRun = entry.Reason == ""
+			effective = append(effective, entry)
+		}
+	}
+
+	return effective, nil
+}
+
+// notRunningReason returns why an item will not run, or "" when it will
+func notRunningReason(item StartupItem, policyDisabled bool) string {
+	if policyDisabled {
+		return "run list disabled by policy"
+	}
+
+	if enabled, err := readApprovalState(item.Name, item.Location); err == nil && !enabled {
+		return "disabled in StartupApproved"
+	}
+
+	executable := CommandExecutable(item.Command)
+	file, err := os.Open(executable)
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return "executable not accessible to the current user"
+	case err != nil:
+		return "executable not found"
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		return "executable not found"
+	}
+
+	return ""
+}
+
+// runListDisabledByPolicy reports whether the machine or user policy turns off
+// processing of a location's run list
+func runListDisabledByPolicy(registryType StartupRegistryType) bool {
+	policy, ok := runListPolicies[registryType]
+	if !ok {
+		return false
+	}
+
+	for _, rootKey := range []registry.Key{registry.LOCAL_MACHINE, registry.CURRENT_USER} {
+		k, err := registry.OpenKey(rootKey, explorerPolicyPath, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		value, _, err := k.GetIntegerValue(policy)
+		k.Close()
+		if err == nil && value != 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// IsElevated reports whether the current process runs with an elevated token,
+// which writing the all-users locations under HKLM requires
+func IsElevated() (bool, error) {
+	var token windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_QUERY, &token); err != nil {
+		return false, fmt.Errorf("failed to open process token: %w", err)
+	}
+	defer token.Close()
+
+	return token.IsElevated(), nil
+}
+
+// requireElevation fails with ErrElevationRequired, which also matches
+// ErrAccessDenied, when a location under HKLM is about to be written by a process
+// that is not elevated. Reading those locations needs no elevation
+func requireElevation(registryType StartupRegistryType) error {
+	if _, rootKey := getRegistryPath(registryType); rootKey != registry.LOCAL_MACHINE {
+		return nil
+	}
+
+	elevated, err := IsElevated()
+	if err != nil {
+		return err
+	}
+	if !elevated {
+		return fmt.Errorf("cannot write %s: %w: %w", registryType, ErrElevationRequired, ErrAccessDenied)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ListStartupEntriesDetailed retrieves the entries of a location sorted by name,
+// keeping the value type that ListStartupEntries drops. A missing key yields no entries
+func ListStartupEntriesDetailed(registryType StartupRegistryType) ([]StartupEntryInfo, error) {
+	items, err := listStartupItems(registryType)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]StartupEntryInfo, len(items))
+	for i, item := range items {
+		entries[i] = StartupEntryInfo{
+			Name:         item.Name,
+			Command:      unquoteCommand(item.Command),
+			ValueType:    item.ValueType,
+			RegistryType: registryType,
+		}
+	}
+
+	return entries, nil
+}
+
+// ListAllStartupEntriesDetailed retrieves the entries of every known location as
+// one slice sorted by location and then name. A location that cannot be read is
+// reported in the joined error, the entries of the other locations are still returned
+func ListAllStartupEntriesDetailed() ([]StartupEntryInfo, error) {
+	// List of registry types to check, in the order of the result
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var allEntries []StartupEntryInfo
+	var errs []error
+	for _, registryType := range registryTypes {
+		entries, err := ListStartupEntriesDetailed(registryType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", registryType, err))
+			continue
+		}
+		allEntries = append(allEntries, entries...)
+	}
+
+	return allEntries, errors.Join(errs...)
+}
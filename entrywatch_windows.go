@@ -0,0 +1,124 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// registryEventQuietPeriod is how long a watched key has to stay quiet before its
+// entries are read again. Installers often write several values in a row, those
+// changes are reported as one update
+const registryEventQuietPeriod = 200 * time.Millisecond
+
+// WatchStartupEntries sends the full set of entries of a location every time its
+// values change, until ctx is cancelled, after which the channel is closed.
+// Changes in quick succession are coalesced into a single update sent once the
+// key has been quiet for a moment. Commands are split as ParseStartupEntry does.
+// The channel is also closed when the key can no longer be watched, for example
+// because it was deleted
+func WatchStartupEntries(ctx context.Context, registryType StartupRegistryType) (<-chan []StartupEntry, error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key for change notifications
+	k, err := registry.OpenKey(rootKey, keyPath, registry.NOTIFY)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+
+	changed, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		k.Close()
+		return nil, fmt.Errorf("failed to create change event: %w", err)
+	}
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(changed)
+		k.Close()
+		return nil, fmt.Errorf("failed to create stop event: %w", err)
+	}
+
+	updates := make(chan []StartupEntry)
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		defer close(updates)
+		defer k.Close()
+		defer windows.CloseHandle(changed)
+
+		watchKey(ctx, k, changed, stop, registryType, updates)
+	}()
+
+	// Wake the watcher when the context ends, the stop event outlives it
+	go func() {
+		select {
+		case <-ctx.Done():
+			windows.SetEvent(stop)
+			<-exited
+		case <-exited:
+		}
+		windows.CloseHandle(stop)
+	}()
+
+	return updates, nil
+}
+
+// watchKey waits for changes to the values of k and sends the entries of the
+// location after each quiet period until stop is signalled or watching fails
+func watchKey(ctx context.Context, k registry.Key, changed, stop windows.Handle, registryType StartupRegistryType, updates chan<- []StartupEntry) {
+	// An asynchronous notification is tied to the thread that asked for it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	filter := uint32(windows.REG_NOTIFY_CHANGE_NAME | windows.REG_NOTIFY_CHANGE_LAST_SET)
+	arm := func() bool {
+		return windows.RegNotifyChangeKeyValue(windows.Handle(k), false, filter, changed, true) == nil
+	}
+
+	if !arm() {
+		return
+	}
+	for {
+		signalled, err := windows.WaitForMultipleObjects([]windows.Handle{changed, stop}, false, windows.INFINITE)
+		if err != nil || signalled != windows.WAIT_OBJECT_0 {
+			return
+		}
+
+		// Re-arm straight away so nothing is missed, and wait for the writes to settle
+		for {
+			if !arm() {
+				return
+			}
+			signalled, err = windows.WaitForMultipleObjects([]windows.Handle{changed, stop}, false, uint32(registryEventQuietPeriod/time.Millisecond))
+			if err != nil || signalled == windows.WAIT_OBJECT_0+1 {
+				return
+			}
+			if signalled == uint32(windows.WAIT_TIMEOUT) {
+				break
+			}
+		}
+
+		items, err := listStartupItems(registryType)
+		if err != nil {
+			return
+		}
+		entries := make([]StartupEntry, len(items))
+		for i, item := range items {
+			entries[i] = ParseStartupEntry(item.Name, item.Command)
+		}
+
+		select {
+		case updates <- entries:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
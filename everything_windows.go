@@ -0,0 +1,136 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// ListEverything returns every startup item of the current user and the machine
+// in a single slice, sorted by source, location, view and name. It covers:
+//
+//   - the Run and RunOnce keys of HKCU and HKLM
+//   - the HKLM keys in both the 64-bit and the 32-bit (WOW6432Node) view
+//   - the Policies\Explorer\Run keys of HKCU and HKLM, flagged as Enforced
+//   - the current user's and the all-users Startup folders
+//
+// HKCU keys are shared between the views and are read once through the default
+// view, as are the HKLM keys on 32-bit Windows, which has a single view. An
+// entry found in both HKLM views with the same command and value type is merged
+// into one item whose Views lists both; the names are compared ignoring case.
+// Same-named entries whose commands differ start different programs and stay
+// separate items, FindCrossViewDuplicates reports those. Scheduled tasks,
+// services and other users' hives are not included. None of the sources require
+// elevation to read; a source that is missing contributes no items
+func ListEverything() ([]StartupItem, error) {
+	var items []StartupItem
+
+	for _, registryType := range []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+		CurrentUserPolicyRun,
+		AllUsersPolicyRun,
+	} {
+		views := []RegistryView{DefaultView}
+		if _, rootKey := getRegistryPath(registryType); rootKey == registry.LOCAL_MACHINE && is64BitWindows() {
+			views = []RegistryView{View64, View32}
+		}
+
+		var locationItems []StartupItem
+		byName := make(map[string]int)
+		for _, view := range views {
+			viewItems, err := listStartupItemsInView(registryType, view)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, item := range viewItems {
+				// The same entry in both views is one item that records both
+				key := strings.ToLower(item.Name)
+				if i, ok := byName[key]; ok && locationItems[i].Command == item.Command && locationItems[i].ValueType == item.ValueType {
+					locationItems[i].Views = append(locationItems[i].Views, view)
+					continue
+				}
+
+				item.Enforced = registryType == CurrentUserPolicyRun || registryType == AllUsersPolicyRun
+				item.Views = []RegistryView{item.View}
+				byName[key] = len(locationItems)
+				locationItems = append(locationItems, item)
+			}
+		}
+		items = append(items, locationItems...)
+	}
+
+	for _, folder := range []struct {
+		id     *windows.KNOWNFOLDERID
+		source StartupSource
+	}{
+		{windows.FOLDERID_Startup, SourceUserStartupFolder},
+		{windows.FOLDERID_CommonStartup, SourceCommonStartupFolder},
+	} {
+		folderItems, err := listStartupFolderItems(folder.id, folder.source)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, folderItems...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Location != b.Location {
+			return a.Location < b.Location
+		}
+		if a.View != b.View {
+			return a.View < b.View
+		}
+		return a.Name < b.Name
+	})
+
+	return items, nil
+}
+
+// listStartupFolderItems lists the files in a Startup folder. The item command is
+// the full path of the file, shortcuts are not resolved. A missing folder yields
+// no items
+func listStartupFolderItems(id *windows.KNOWNFOLDERID, source StartupSource) ([]StartupItem, error) {
+	dir, err := windows.KnownFolderPath(id, 0)
+	if err != nil {
+		return nil, nil
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read startup folder: %w", err)
+	}
+
+	var items []StartupItem
+	for _, file := range files {
+		// Explorer keeps its folder settings in desktop.ini, it is not started
+		if file.IsDir() || strings.EqualFold(file.Name(), "desktop.ini") {
+			continue
+		}
+		items = append(items, StartupItem{
+			Name:    file.Name(),
+			Command: filepath.Join(dir, file.Name()),
+			Source:  source,
+		})
+	}
+
+	return items, nil
+}
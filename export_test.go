@@ -0,0 +1,24 @@
+package winstartupreg
+
+// Internal helpers exposed to the external test package
+
+// ParseLogonTaskXML exposes parseLogonTaskXML
+var ParseLogonTaskXML = parseLogonTaskXML
+
+// ParseRegFile exposes parseRegFile
+var ParseRegFile = parseRegFile
+
+// ApplyRegFile exposes applyRegFile
+var ApplyRegFile = applyRegFile
+
+// SplitProbedCommand exposes splitProbedCommand
+var SplitProbedCommand = splitProbedCommand
+
+// OrderRunOnceExKeys exposes orderRunOnceExKeys
+var OrderRunOnceExKeys = orderRunOnceExKeys
+
+// RegFileOp and EntryValue expose the types parseRegFile returns
+type (
+	RegFileOp  = regFileOp
+	EntryValue = entryValue
+)
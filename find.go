@@ -0,0 +1,34 @@
+package winstartupreg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// FindEntries returns the entries of a location whose value name matches a glob
+// pattern. The syntax is that of filepath.Match: * matches any run of characters
+// except a path separator, ? matches one character and [...] matches a character
+// class. Matching is case-insensitive, like registry value names
+func FindEntries(pattern string, registryType StartupRegistryType) (map[string]string, error) {
+	pattern = strings.ToLower(pattern)
+
+	// Reject malformed patterns up front instead of silently matching nothing
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	entries, err := ListStartupEntries(registryType)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make(map[string]string)
+	for name, command := range entries {
+		if ok, _ := filepath.Match(pattern, strings.ToLower(name)); ok {
+			matches[name] = command
+		}
+	}
+
+	return matches, nil
+}
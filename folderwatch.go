@@ -0,0 +1,52 @@
+package winstartupreg
+
+// FolderChangeOp is the kind of change reported for a Startup folder shortcut
+type FolderChangeOp int
+
+const (
+	// FolderShortcutAdded is a shortcut that appeared, including one renamed into place
+	FolderShortcutAdded FolderChangeOp = iota + 1
+	// FolderShortcutRemoved is a shortcut that was deleted or renamed away
+	FolderShortcutRemoved
+	// FolderShortcutModified is a shortcut whose contents changed
+	FolderShortcutModified
+)
+
+// String returns the name of the change, e.g. "Added"
+func (op FolderChangeOp) String() string {
+	switch op {
+	case FolderShortcutAdded:
+		return "Added"
+	case FolderShortcutRemoved:
+		return "Removed"
+	case FolderShortcutModified:
+		return "Modified"
+	default:
+		return "Unknown"
+	}
+}
+
+// FolderChangeEvent describes a change to a shortcut in a Startup folder
+type FolderChangeEvent struct {
+	Source StartupSource  // SourceUserStartupFolder or SourceCommonStartupFolder
+	Name   string         // File name of the shortcut, e.g. "App.lnk"
+	Path   string         // Full path of the shortcut
+	Op     FolderChangeOp // What happened to it
+	Target string         // Resolved target, empty for removals or unreadable shortcuts
+}
+
+// mergeFolderChange combines a pending change of a file with a newer one, so a
+// burst of notifications yields a single event. It reports false when the two
+// cancel out, as for a file created and deleted again before the burst ended
+func mergeFolderChange(pending, next FolderChangeOp) (FolderChangeOp, bool) {
+	switch {
+	case pending == FolderShortcutAdded && next == FolderShortcutRemoved:
+		return 0, false
+	case pending == FolderShortcutAdded:
+		return FolderShortcutAdded, true
+	case pending == FolderShortcutRemoved && next == FolderShortcutAdded:
+		return FolderShortcutModified, true
+	default:
+		return next, true
+	}
+}
@@ -0,0 +1,244 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// folderEventQuietPeriod is how long a Startup folder has to stay quiet before
+// the changes collected so far are delivered. Copying or saving a shortcut fires
+// several notifications in quick succession, they are reported as one event
+const folderEventQuietPeriod = 200 * time.Millisecond
+
+// folderChange is a raw notification for one file of a watched folder
+type folderChange struct {
+	source StartupSource
+	path   string
+	op     FolderChangeOp
+}
+
+// WatchStartupFolders reports changes to the .lnk files of the current user's and
+// the all-users Startup folders until ctx is cancelled, after which the channel
+// is closed. Rapid notifications for the same file are coalesced into a single
+// event delivered once the folder has been quiet for a moment. Events carry the
+// resolved shortcut target, except for removals. A Startup folder that does not
+// exist is not watched
+func WatchStartupFolders(ctx context.Context) (<-chan FolderChangeEvent, error) {
+	// Stops every folder watcher when the context ends
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stop event: %w", err)
+	}
+
+	var handles []windows.Handle
+	var sources []StartupSource
+	var dirs []string
+	closeAll := func() {
+		for _, h := range handles {
+			windows.CloseHandle(h)
+		}
+		windows.CloseHandle(stop)
+	}
+
+	for _, folder := range []struct {
+		id     *windows.KNOWNFOLDERID
+		source StartupSource
+	}{
+		{windows.FOLDERID_Startup, SourceUserStartupFolder},
+		{windows.FOLDERID_CommonStartup, SourceCommonStartupFolder},
+	} {
+		dir, err := windows.KnownFolderPath(folder.id, 0)
+		if err != nil {
+			continue
+		}
+		h, err := openDirectoryForWatch(dir)
+		if err != nil {
+			if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) || errors.Is(err, windows.ERROR_PATH_NOT_FOUND) {
+				continue
+			}
+			closeAll()
+			return nil, fmt.Errorf("failed to open startup folder %s: %w", dir, err)
+		}
+		handles = append(handles, h)
+		sources = append(sources, folder.source)
+		dirs = append(dirs, dir)
+	}
+
+	changes := make(chan folderChange)
+	events := make(chan FolderChangeEvent)
+
+	var wg sync.WaitGroup
+	for i := range handles {
+		wg.Add(1)
+		go func(h windows.Handle, source StartupSource, dir string) {
+			defer wg.Done()
+			watchDirectory(h, stop, source, dir, changes)
+		}(handles[i], sources[i], dirs[i])
+	}
+
+	// Wake the watchers when the context ends and close the raw channel once they are gone
+	go func() {
+		<-ctx.Done()
+		windows.SetEvent(stop)
+		wg.Wait()
+		closeAll()
+		close(changes)
+	}()
+
+	go coalesceFolderChanges(ctx, changes, events)
+
+	return events, nil
+}
+
+// openDirectoryForWatch opens a directory handle for overlapped ReadDirectoryChangesW calls
+func openDirectoryForWatch(dir string) (windows.Handle, error) {
+	p, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+
+	return windows.CreateFile(p,
+		windows.FILE_LIST_DIRECTORY,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OVERLAPPED,
+		0)
+}
+
+// watchDirectory sends a folderChange for every .lnk notification of a directory
+// until stop is signalled or the directory can no longer be watched
+func watchDirectory(h, stop windows.Handle, source StartupSource, dir string, changes chan<- folderChange) {
+	done, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return
+	}
+	defer windows.CloseHandle(done)
+
+	// DWORD aligned as ReadDirectoryChangesW requires
+	buf := make([]uint32, 16*1024)
+	mask := uint32(windows.FILE_NOTIFY_CHANGE_FILE_NAME | windows.FILE_NOTIFY_CHANGE_LAST_WRITE | windows.FILE_NOTIFY_CHANGE_SIZE)
+
+	for {
+		overlapped := windows.Overlapped{HEvent: done}
+		if err := windows.ReadDirectoryChanges(h, (*byte)(unsafe.Pointer(&buf[0])), uint32(len(buf)*4), false, mask, nil, &overlapped, 0); err != nil {
+			return
+		}
+
+		signalled, err := windows.WaitForMultipleObjects([]windows.Handle{done, stop}, false, windows.INFINITE)
+		if err != nil || signalled != windows.WAIT_OBJECT_0 {
+			// Stopped: cancel the pending read and wait for it to finish with the buffer
+			var n uint32
+			windows.CancelIoEx(h, &overlapped)
+			windows.GetOverlappedResult(h, &overlapped, &n, true)
+			return
+		}
+
+		var n uint32
+		if err := windows.GetOverlappedResult(h, &overlapped, &n, false); err != nil {
+			return
+		}
+
+		// A zero length means the kernel buffer overflowed and the details are lost
+		for offset := uint32(0); n > 0; {
+			info := (*windows.FileNotifyInformation)(unsafe.Pointer(uintptr(unsafe.Pointer(&buf[0])) + uintptr(offset)))
+			name := windows.UTF16ToString(unsafe.Slice(&info.FileName, info.FileNameLength/2))
+
+			if op, ok := folderChangeOp(info.Action); ok && strings.EqualFold(filepath.Ext(name), ".lnk") {
+				changes <- folderChange{source: source, path: filepath.Join(dir, name), op: op}
+			}
+
+			if info.NextEntryOffset == 0 {
+				break
+			}
+			offset += info.NextEntryOffset
+		}
+	}
+}
+
+// folderChangeOp maps a FILE_ACTION_* code to the change it stands for
+func folderChangeOp(action uint32) (FolderChangeOp, bool) {
+	switch action {
+	case windows.FILE_ACTION_ADDED, windows.FILE_ACTION_RENAMED_NEW_NAME:
+		return FolderShortcutAdded, true
+	case windows.FILE_ACTION_REMOVED, windows.FILE_ACTION_RENAMED_OLD_NAME:
+		return FolderShortcutRemoved, true
+	case windows.FILE_ACTION_MODIFIED:
+		return FolderShortcutModified, true
+	default:
+		return 0, false
+	}
+}
+
+// coalesceFolderChanges merges the raw changes of each file and delivers them as
+// events once no new change arrived for folderEventQuietPeriod. It keeps reading
+// changes until the watchers close the channel, so they never block, and then
+// closes events
+func coalesceFolderChanges(ctx context.Context, changes <-chan folderChange, events chan<- FolderChangeEvent) {
+	defer close(events)
+
+	type key struct {
+		source StartupSource
+		path   string
+	}
+	pending := make(map[key]FolderChangeOp)
+	var order []key
+
+	timer := time.NewTimer(folderEventQuietPeriod)
+	timer.Stop()
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			k := key{change.source, change.path}
+			previous, seen := pending[k]
+			if !seen {
+				pending[k] = change.op
+				order = append(order, k)
+			} else if op, keep := mergeFolderChange(previous, change.op); keep {
+				pending[k] = op
+			} else {
+				delete(pending, k)
+			}
+			timer.Reset(folderEventQuietPeriod)
+
+		case <-timer.C:
+			for _, k := range order {
+				op, ok := pending[k]
+				if !ok {
+					continue
+				}
+				delete(pending, k)
+
+				event := FolderChangeEvent{Source: k.source, Name: filepath.Base(k.path), Path: k.path, Op: op}
+				if op != FolderShortcutRemoved {
+					event.Target, _ = resolveShortcut(k.path)
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					// Drain the raw channel so the watchers can finish
+					for range changes {
+					}
+					return
+				}
+			}
+			order = nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// FindByHashAllUsers hunts for Run and RunOnce entries of every user hive loaded
+// under HKEY_USERS whose executable has the given SHA-256 digest. Matches are
+// keyed by the SID of the hive they were found in and carry the CurrentUser
+// location they correspond to. Executables are hashed once per scan however many
+// users share them. Environment variables are expanded with the environment of
+// each user, as ExpandForUser does
+func FindByHashAllUsers(sha256 string) (map[string][]StartupItem, error) {
+	// Validate input
+	want, err := hex.DecodeString(sha256)
+	if err != nil || len(want) != 32 {
+		return nil, fmt.Errorf("invalid SHA-256 digest '%s'", sha256)
+	}
+	digest := hex.EncodeToString(want)
+
+	sids, err := loadedUserSIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	// Digests of the executables seen so far, "" for files that cannot be read
+	hashes := make(map[string]string)
+
+	matches := make(map[string][]StartupItem)
+	var errs []error
+	for _, sid := range sids {
+		env, err := loadUserEnvironment(sid)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sid, err))
+			continue
+		}
+
+		for _, registryType := range []StartupRegistryType{CurrentUserRun, CurrentUserRunOnce} {
+			keyPath, _ := getRegistryPath(registryType)
+			items, err := readStartupItems(registry.USERS, sid+`\`+keyPath, registryType, DefaultView)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", sid, err))
+				continue
+			}
+
+			for _, item := range items {
+				executable := strings.ToLower(filepath.Clean(commandExecutable(item.Command, env.lookup)))
+				hash, ok := hashes[executable]
+				if !ok {
+					hash, _ = fileSHA256(executable)
+					hashes[executable] = hash
+				}
+				if hash == digest {
+					matches[sid] = append(matches[sid], item)
+				}
+			}
+		}
+	}
+
+	return matches, errors.Join(errs...)
+}
+
+// loadedUserSIDs lists the SIDs of the user hives loaded under HKEY_USERS,
+// leaving out the separate _Classes hives
+func loadedUserSIDs() ([]string, error) {
+	k, err := registry.OpenKey(registry.USERS, "", registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer k.Close()
+
+	names, err := k.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subkey names: %w", err)
+	}
+
+	var sids []string
+	for _, name := range names {
+		if !strings.HasSuffix(strings.ToLower(name), "_classes") {
+			sids = append(sids, name)
+		}
+	}
+
+	return sids, nil
+}
+
+// fileSHA256 returns the lowercase hex SHA-256 digest of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,88 @@
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LocatedEntry is a startup entry together with the location it belongs in
+type LocatedEntry struct {
+	StartupEntry
+	Location StartupRegistryType
+}
+
+// ImportValidation is the outcome of validating one entry of an import
+type ImportValidation struct {
+	Entry  LocatedEntry
+	Valid  bool
+	Reason string // Why the entry would fail, empty when Valid is true
+}
+
+// ValidateImport checks every entry of an import the way AddStartupEntry would,
+// without touching the registry: the entry must pass StartupEntry.Validate, its
+// location must be known and its executable must exist. Two entries with the same
+// name and location but different commands conflict, and the later one is
+// reported as invalid. The result has one validation per entry, in input order
+func ValidateImport(entries []LocatedEntry) []ImportValidation {
+	validations := make([]ImportValidation, len(entries))
+	commands := make(map[StartupRegistryType]map[string]string)
+
+	for i, entry := range entries {
+		validations[i] = ImportValidation{Entry: entry, Valid: true}
+
+		err := validateLocatedEntry(entry)
+		commandLine := entryCommandLine(entry.Command, entry.Command, entry.Args)
+		if command, ok := commands[entry.Location][entry.Name]; ok && err == nil && command != commandLine {
+			err = fmt.Errorf("entry '%s' appears more than once for %s with different commands", entry.Name, entry.Location)
+		}
+		if err != nil {
+			validations[i].Valid = false
+			validations[i].Reason = err.Error()
+			continue
+		}
+
+		if commands[entry.Location] == nil {
+			commands[entry.Location] = make(map[string]string)
+		}
+		commands[entry.Location][entry.Name] = commandLine
+	}
+
+	return validations
+}
+
+// validateLocatedEntry checks a single import entry on its own
+func validateLocatedEntry(entry LocatedEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if _, ok := registryTypeNames[entry.Location.String()]; !ok {
+		return fmt.Errorf("unknown startup registry type %d", int(entry.Location))
+	}
+	_, err := resolveCommand(entry.Command)
+	return err
+}
+
+// ApplyImport adds every entry of an import after validating all of them with
+// ValidateImport. If any entry is invalid nothing is written and the error lists
+// the problems. Otherwise the entries are added in order, and the error, if any,
+// lists the entries that could not be written
+func ApplyImport(entries []LocatedEntry) error {
+	var problems []error
+	for _, validation := range ValidateImport(entries) {
+		if !validation.Valid {
+			problems = append(problems, fmt.Errorf("%s\\%s: %s", validation.Entry.Location, validation.Entry.Name, validation.Reason))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("import rejected, %d of %d entries are invalid: %w", len(problems), len(entries), errors.Join(problems...))
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if err := AddStartupEntry(entry.StartupEntry, entry.Location); err != nil {
+			errs = append(errs, fmt.Errorf("%s\\%s: %w", entry.Location, entry.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
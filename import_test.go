@@ -0,0 +1,45 @@
+package winstartupreg_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+var _ = Describe("Validating Imports", func() {
+	It("Should report every problem without writing anything", func() {
+		executable := filepath.Join(GinkgoT().TempDir(), "app.exe")
+		Expect(os.WriteFile(executable, nil, 0o755)).To(Succeed())
+
+		entries := []winstartupreg.LocatedEntry{
+			{StartupEntry: winstartupreg.StartupEntry{Name: "Good", Command: executable}, Location: winstartupreg.CurrentUserRun},
+			{StartupEntry: winstartupreg.StartupEntry{Name: "", Command: executable}, Location: winstartupreg.CurrentUserRun},
+			{StartupEntry: winstartupreg.StartupEntry{Name: "Missing", Command: filepath.Join(filepath.Dir(executable), "missing.exe")}, Location: winstartupreg.CurrentUserRun},
+			{StartupEntry: winstartupreg.StartupEntry{Name: "Nowhere", Command: executable}, Location: winstartupreg.StartupRegistryType(99)},
+			{StartupEntry: winstartupreg.StartupEntry{Name: "Good", Command: executable + " --other"}, Location: winstartupreg.CurrentUserRun},
+		}
+
+		validations := winstartupreg.ValidateImport(entries)
+		Expect(validations).To(HaveLen(len(entries)))
+		Expect(validations[0].Valid).To(BeTrue())
+		Expect(validations[0].Reason).To(BeEmpty())
+		for _, validation := range validations[1:] {
+			Expect(validation.Valid).To(BeFalse())
+			Expect(validation.Reason).ToNot(BeEmpty())
+		}
+
+		Expect(winstartupreg.ApplyImport(entries)).To(MatchError(ContainSubstring("4 of 5 entries are invalid")))
+	})
+
+	It("Should reject a directory as the executable", func() {
+		validations := winstartupreg.ValidateImport([]winstartupreg.LocatedEntry{
+			{StartupEntry: winstartupreg.StartupEntry{Name: "Folder", Command: GinkgoT().TempDir()}, Location: winstartupreg.CurrentUserRun},
+		})
+		Expect(validations[0].Valid).To(BeFalse())
+		Expect(validations[0].Reason).To(ContainSubstring("not an executable"))
+	})
+})
@@ -0,0 +1,33 @@
+package winstartupreg
+
+import (
+	"sort"
+	"time"
+)
+
+// timedItem is a startup item together with the best guess of when it was written
+type timedItem struct {
+	item StartupItem
+	at   time.Time
+}
+
+// groupByTime clusters items whose times follow each other with gaps of at most
+// window, so a chain of entries written a few seconds apart ends up in one group.
+// Each group is keyed by its earliest time and keeps the items in time order
+func groupByTime(items []timedItem, window time.Duration) map[time.Time][]StartupItem {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].at.Before(items[j].at)
+	})
+
+	groups := make(map[time.Time][]StartupItem)
+	var start, last time.Time
+	for i, timed := range items {
+		if i == 0 || timed.at.Sub(last) > window {
+			start = timed.at
+		}
+		groups[start] = append(groups[start], timed.item)
+		last = timed.at
+	}
+
+	return groups
+}
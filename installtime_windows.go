@@ -0,0 +1,52 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EntriesGroupedByApproxInstallTime groups the entries of the known locations
+// that were probably added together, for example by one installer. This is a
+// heuristic: the registry keeps no creation time per value, so each entry is
+// dated by the timestamp of its StartupApproved blob where it has one and by the
+// last-write time of its key otherwise. Entries whose times are chained by gaps
+// of at most window share a group, keyed by the earliest time in it. A later
+// write to a key re-dates every entry in it that has no blob
+func EntriesGroupedByApproxInstallTime(window time.Duration) (map[time.Time][]StartupItem, error) {
+	if window < 0 {
+		return nil, fmt.Errorf("window cannot be negative")
+	}
+
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var timed []timedItem
+	var errs []error
+	for _, registryType := range registryTypes {
+		lastWrite, _, items, err := readLocationState(registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, item := range items {
+			at := lastWrite
+			if blob, err := readApprovalBlob(item.Name, registryType); err == nil {
+				if stamp, ok := approvalBlobTime(blob); ok {
+					at = stamp
+				}
+			}
+			timed = append(timed, timedItem{item: item, at: at})
+		}
+	}
+
+	return groupByTime(timed, window), errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package winstartupreg
+
+// StartupItem is a startup entry tagged with the location it was found in
+type StartupItem struct {
+	Name      string              // Value name of the entry
+	Command   string              // Stored command, environment variables are not expanded
+	ValueType uint32              // Registry value type, e.g. registry.SZ or registry.EXPAND_SZ
+	Location  StartupRegistryType // Registry location the entry was read from
+	Enforced  bool                // Mandated by policy rather than added by a user or installer
+	Source    StartupSource       // Kind of place the item was found in
+	View      RegistryView        // Registry view the item was read through, zero for the default view
+	Views     []RegistryView      // Set by ListEverything: every view the same entry was found in, View first
+}
+
+// StartupEntryInfo is a startup entry together with its value type and the
+// location it was read from, a flat row for tables and reports
+type StartupEntryInfo struct {
+	Name         string              // Value name of the entry
+	Command      string              // Command as ListStartupEntries returns it, environment variables are not expanded
+	ValueType    uint32              // Registry value type, registry.SZ or registry.EXPAND_SZ
+	RegistryType StartupRegistryType // Registry location the entry was read from
+}
+
+// StartupSource identifies the kind of place a startup item was found in
+type StartupSource int
+
+const (
+	// SourceRegistry is a Run, RunOnce or policy key, given by the item's Location
+	SourceRegistry StartupSource = iota
+	// SourceUserStartupFolder is the current user's Startup folder (shell:startup)
+	SourceUserStartupFolder
+	// SourceCommonStartupFolder is the all-users Startup folder (shell:common startup)
+	SourceCommonStartupFolder
+)
+
+// String returns the name of the source
+func (s StartupSource) String() string {
+	switch s {
+	case SourceRegistry:
+		return "Registry"
+	case SourceUserStartupFolder:
+		return "UserStartupFolder"
+	case SourceCommonStartupFolder:
+		return "CommonStartupFolder"
+	default:
+		return "Unknown"
+	}
+}
+
+// RegistryView selects the 64-bit or 32-bit view of a LOCAL_MACHINE key on
+// 64-bit Windows. The values are the KEY_WOW64_64KEY and KEY_WOW64_32KEY
+// access flags, so a view can be ORed straight into an access mask
+type RegistryView uint32
+
+const (
+	// DefaultView opens keys in the view native to the calling process
+	DefaultView RegistryView = 0
+	// View64 opens the 64-bit view (registry.WOW64_64KEY)
+	View64 RegistryView = 0x0100
+	// View32 opens the 32-bit view under WOW6432Node (registry.WOW64_32KEY)
+	View32 RegistryView = 0x0200
+)
+
+// String returns the name of the view
+func (v RegistryView) String() string {
+	switch v {
+	case DefaultView:
+		return "Default"
+	case View64:
+		return "64-bit"
+	case View32:
+		return "32-bit"
+	default:
+		return "Unknown"
+	}
+}
+
+// EffectiveEntry is a startup item together with whether it will actually run
+// at the current user's next logon
+type EffectiveEntry struct {
+	StartupItem
+	WillRun bool   // The entry will be launched at the next logon
+	Reason  string // Why the entry will not run, empty when WillRun is true
+}
+
+// CrossViewDuplicate is an entry name present in both the 64-bit and the 32-bit
+// view of an HKLM startup key. Windows launches both, so the program may start twice
+type CrossViewDuplicate struct {
+	Location  StartupRegistryType // AllUsersRun or AllUsersRunOnce
+	Name      string              // Value name found in both views
+	Command64 string              // Command in the 64-bit view
+	Command32 string              // Command in the 32-bit (WOW6432Node) view
+}
@@ -0,0 +1,98 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// ListEnforcedStartupItems retrieves the startup items mandated by policy, which
+// live in the Policies\Explorer\Run keys of the machine and of the current user.
+// Every item is flagged as Enforced. Group Policy Preferences registry items are
+// written straight into the regular Run keys and cannot be told apart, so they
+// are not reported here
+func ListEnforcedStartupItems() ([]StartupItem, error) {
+	var items []StartupItem
+
+	for _, registryType := range []StartupRegistryType{AllUsersPolicyRun, CurrentUserPolicyRun} {
+		policyItems, err := listStartupItems(registryType)
+		if err != nil {
+			return nil, err
+		}
+		for i := range policyItems {
+			policyItems[i].Enforced = true
+		}
+		items = append(items, policyItems...)
+	}
+
+	return items, nil
+}
+
+// ListStartupItems retrieves the entries of a location sorted by name together
+// with their registry value types, so REG_EXPAND_SZ commands can be told apart
+// from REG_SZ ones. Commands are returned unexpanded. A missing key yields no items
+func ListStartupItems(registryType StartupRegistryType) ([]StartupItem, error) {
+	return listStartupItems(registryType)
+}
+
+// ListStartupItemsInView is ListStartupItems through an explicit registry view,
+// so both the 64-bit key and its WOW6432Node twin can be read from any process
+func ListStartupItemsInView(registryType StartupRegistryType, view RegistryView) ([]StartupItem, error) {
+	return listStartupItemsInView(registryType, view)
+}
+
+// listStartupItems reads the string values of a location sorted by name. A
+// missing key yields no items rather than an error
+func listStartupItems(registryType StartupRegistryType) ([]StartupItem, error) {
+	return listStartupItemsInView(registryType, DefaultView)
+}
+
+// listStartupItemsInView reads the string values of a location through the given registry view
+func listStartupItemsInView(registryType StartupRegistryType, view RegistryView) ([]StartupItem, error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	return readStartupItems(rootKey, keyPath, registryType, view)
+}
+
+// readStartupItems reads the string values of the key at keyPath under rootKey
+// sorted by name, labelling them with registryType. A missing key yields no items
+func readStartupItems(rootKey registry.Key, keyPath string, registryType StartupRegistryType, view RegistryView) ([]StartupItem, error) {
+	// Open the registry key with read access
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE|uint32(view))
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer k.Close()
+
+	// Get all value names
+	valueNames, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value names: %w", err)
+	}
+	sort.Strings(valueNames)
+
+	var items []StartupItem
+	for _, name := range valueNames {
+		command, valType, err := k.GetStringValue(name)
+		if err != nil {
+			continue
+		}
+		items = append(items, StartupItem{
+			Name:      name,
+			Command:   command,
+			ValueType: valType,
+			Location:  registryType,
+			View:      view,
+		})
+	}
+
+	return items, nil
+}
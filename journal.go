@@ -0,0 +1,158 @@
+package winstartupreg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalOperation names a change recorded in the journal
+type JournalOperation string
+
+const (
+	JournalAdd     JournalOperation = "add"
+	JournalRemove  JournalOperation = "remove"
+	JournalEnable  JournalOperation = "enable"
+	JournalDisable JournalOperation = "disable"
+)
+
+// JournalEntry is one change recorded in the journal. For adds and removes Before
+// and After hold the command, empty when the entry did not exist. For enables and
+// disables they hold the state, "enabled" or "disabled"
+type JournalEntry struct {
+	Time      time.Time           `json:"time"`
+	Operation JournalOperation    `json:"operation"`
+	Location  StartupRegistryType `json:"location"`
+	Name      string              `json:"name"`
+	Before    string              `json:"before,omitempty"`
+	After     string              `json:"after,omitempty"`
+}
+
+// JournalOption configures SetJournal
+type JournalOption func(*journalOptions)
+
+type journalOptions struct {
+	required bool
+}
+
+// WithJournalRequired makes journal failures fatal: an operation whose journal
+// file cannot be opened fails before it changes anything, and one whose record
+// cannot be written returns the error after its change was made. By default
+// journal failures are ignored and the operation goes ahead
+func WithJournalRequired() JournalOption {
+	return func(o *journalOptions) {
+		o.required = true
+	}
+}
+
+// journalConfig is set by SetJournal
+var journalConfig struct {
+	sync.Mutex
+	path    string
+	options journalOptions
+}
+
+// SetJournal makes every successful add, remove, enable and disable append a
+// JournalEntry to the file at path, one JSON object per line, for an audit trail
+// of the changes made through this package. Other writes to an entry, such as
+// sync updates, swaps, repairs and restores, are recorded as adds. An empty path
+// turns the journal off
+func SetJournal(path string, opts ...JournalOption) {
+	options := journalOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	journalConfig.Lock()
+	defer journalConfig.Unlock()
+	journalConfig.path = path
+	journalConfig.options = options
+}
+
+// journalFile is an open journal, a nil journalFile records nothing
+type journalFile struct {
+	f        *os.File
+	required bool
+}
+
+// openJournal opens the configured journal for appending. It returns nil when no
+// journal is set, or when it cannot be opened and is not required
+func openJournal() (*journalFile, error) {
+	journalConfig.Lock()
+	path, options := journalConfig.path, journalConfig.options
+	journalConfig.Unlock()
+
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		if options.required {
+			return nil, fmt.Errorf("failed to open journal: %w", err)
+		}
+		return nil, nil
+	}
+
+	return &journalFile{f: f, required: options.required}, nil
+}
+
+// record appends an entry stamped with the current time and flushes it to disk
+func (j *journalFile) record(entry JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+
+	entry.Time = time.Now().UTC()
+	data, err := json.Marshal(entry)
+	if err == nil {
+		if _, err = j.f.Write(append(data, '\n')); err == nil {
+			err = j.f.Sync()
+		}
+	}
+
+	if err != nil && j.required {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Close closes the journal file
+func (j *journalFile) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.f.Close()
+}
+
+// ReadJournal parses a journal written through SetJournal, oldest entry first
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journal line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,42 @@
+package winstartupreg_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+var _ = Describe("Reading the Journal", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "journal.jsonl")
+	})
+
+	It("Should parse one entry per line and skip blank lines", func() {
+		Expect(os.WriteFile(path, []byte(
+			`{"time":"2024-05-01T10:00:00Z","operation":"add","location":"CurrentUserRun","name":"MyApp","after":"C:\\app.exe"}`+"\n\n"+
+				`{"time":"2024-05-01T10:05:00Z","operation":"disable","location":"CurrentUserRun","name":"MyApp","before":"enabled","after":"disabled"}`+"\n",
+		), 0o600)).To(Succeed())
+
+		entries, err := winstartupreg.ReadJournal(path)
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Operation).To(Equal(winstartupreg.JournalAdd))
+		Expect(entries[0].Location).To(Equal(winstartupreg.CurrentUserRun))
+		Expect(entries[0].After).To(Equal(`C:\app.exe`))
+		Expect(entries[1].Operation).To(Equal(winstartupreg.JournalDisable))
+		Expect(entries[1].Before).To(Equal("enabled"))
+	})
+
+	It("Should report the line of a malformed entry", func() {
+		Expect(os.WriteFile(path, []byte(`{"operation":"add"}`+"\n"+`not json`+"\n"), 0o600)).To(Succeed())
+
+		_, err := winstartupreg.ReadJournal(path)
+		Expect(err).To(MatchError(ContainSubstring("line 2")))
+	})
+})
@@ -0,0 +1,84 @@
+package winstartupreg
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// knownAppsJSON is the bundled table of well-known startup programs
+//
+//go:embed knownapps.json
+var knownAppsJSON []byte
+
+// knownApp maps executables matching a glob pattern to a friendly description
+type knownApp struct {
+	Match       string `json:"match"`
+	Description string `json:"description"`
+}
+
+var (
+	knownAppsMu sync.RWMutex
+	knownApps   []knownApp
+
+	// Apps added through RegisterKnownApp, consulted before the bundled table
+	registeredApps []knownApp
+)
+
+func init() {
+	if err := json.Unmarshal(knownAppsJSON, &knownApps); err != nil {
+		panic(fmt.Sprintf("winstartupreg: invalid bundled known-apps table: %v", err))
+	}
+}
+
+// DescribeEntry returns a user-friendly description of the program an entry
+// starts, such as "OneDrive - Microsoft cloud file sync", by matching the file
+// name of its executable against the known apps. Mappings added through
+// RegisterKnownApp take precedence over the bundled table, later ones first
+func DescribeEntry(entry StartupEntry) (description string, known bool) {
+	executable := CommandExecutable(entry.Command)
+	if i := strings.LastIndexAny(executable, `\/`); i >= 0 {
+		executable = executable[i+1:]
+	}
+	executable = strings.ToLower(executable)
+	if executable == "" {
+		return "", false
+	}
+
+	knownAppsMu.RLock()
+	defer knownAppsMu.RUnlock()
+
+	for i := len(registeredApps) - 1; i >= 0; i-- {
+		if ok, _ := filepath.Match(registeredApps[i].Match, executable); ok {
+			return registeredApps[i].Description, true
+		}
+	}
+	for _, app := range knownApps {
+		if ok, _ := filepath.Match(app.Match, executable); ok {
+			return app.Description, true
+		}
+	}
+
+	return "", false
+}
+
+// RegisterKnownApp adds a description for executables whose file name matches
+// matcher, a case-insensitive filepath.Match pattern such as "myagent*.exe".
+// It is safe to call concurrently with DescribeEntry
+func RegisterKnownApp(matcher, description string) error {
+	matcher = strings.ToLower(matcher)
+
+	// Reject malformed patterns up front instead of silently matching nothing
+	if _, err := filepath.Match(matcher, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", matcher, err)
+	}
+
+	knownAppsMu.Lock()
+	defer knownAppsMu.Unlock()
+
+	registeredApps = append(registeredApps, knownApp{Match: matcher, Description: description})
+	return nil
+}
@@ -0,0 +1,31 @@
+package winstartupreg_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+var _ = Describe("Describing Known Apps", func() {
+	It("Should describe a bundled app by its executable name", func() {
+		description, known := winstartupreg.DescribeEntry(winstartupreg.StartupEntry{
+			Name:    "OneDrive",
+			Command: `"C:\Users\Me\AppData\Local\Microsoft\OneDrive\OneDrive.exe" /background`,
+		})
+		Expect(known).To(BeTrue())
+		Expect(description).To(ContainSubstring("OneDrive"))
+	})
+
+	It("Should prefer registered mappings and report unknown apps", func() {
+		Expect(winstartupreg.RegisterKnownApp("MyAgent*.exe", "My Agent - keeps things running")).To(Succeed())
+		Expect(winstartupreg.RegisterKnownApp("[", "broken")).ToNot(Succeed())
+
+		description, known := winstartupreg.DescribeEntry(winstartupreg.StartupEntry{Command: `C:\Tools\myagent-x64.exe --tray`})
+		Expect(known).To(BeTrue())
+		Expect(description).To(Equal("My Agent - keeps things running"))
+
+		_, known = winstartupreg.DescribeEntry(winstartupreg.StartupEntry{Command: `C:\Tools\unheard-of.exe`})
+		Expect(known).To(BeFalse())
+	})
+})
@@ -0,0 +1,22 @@
+package winstartupreg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// launcherScript returns a VBScript that starts command with a hidden window
+// (window style 0) and does not wait for it to finish
+func launcherScript(command string) (string, error) {
+	// A VBScript string literal cannot span lines or hold NULs
+	if strings.ContainsAny(command, "\r\n\x00") {
+		return "", fmt.Errorf("command cannot be embedded in a launcher script: %q", command)
+	}
+
+	literal := `"` + strings.ReplaceAll(command, `"`, `""`) + `"`
+
+	return "' Generated by winstartupreg, do not edit.\r\n" +
+		"' Starts the command below without showing a console window.\r\n" +
+		"Set shell = CreateObject(\"WScript.Shell\")\r\n" +
+		"shell.Run " + literal + ", 0, False\r\n", nil
+}
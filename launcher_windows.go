@@ -0,0 +1,173 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// AddNoWindowStartupEntry registers an entry that starts without a console window
+// flashing up. It generates a small VBScript launcher that runs the command with
+// a hidden window and registers wscript.exe with that script. The script lives in
+// %LOCALAPPDATA%\winstartupreg\launchers, or %ProgramFiles% for the all-users
+// locations so that only administrators can change what every user runs at
+// logon, and is tracked in the entry's metadata so that RemoveStartupEntry
+// deletes it again
+func AddNoWindowStartupEntry(entry StartupEntry, registryType StartupRegistryType) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if err := requireElevation(registryType); err != nil {
+		return err
+	}
+
+	// Validate input
+	if entry.Name == "" {
+		return fmt.Errorf("entry name cannot be empty")
+	}
+	if strings.ContainsAny(entry.Name, `\/:*?"<>|`) {
+		return fmt.Errorf("entry name '%s' cannot be used as a file name", entry.Name)
+	}
+
+	// Normalize and validate command path
+	fullPath, err := resolveCommand(entry.Command)
+	if err != nil {
+		return err
+	}
+
+	script, err := launcherScript(composeCommand(fullPath, joinArgs(entry.Args)))
+	if err != nil {
+		return err
+	}
+
+	// Write the launcher and make sure it reads back intact
+	dir, err := launcherDir(registryType)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create launcher directory: %w", err)
+	}
+	scriptPath := filepath.Join(dir, entry.Name+".vbs")
+	if err := os.WriteFile(scriptPath, []byte(script), 0o644); err != nil {
+		return fmt.Errorf("failed to write launcher script: %w", err)
+	}
+	if written, err := os.ReadFile(scriptPath); err != nil || string(written) != script {
+		os.Remove(scriptPath)
+		return fmt.Errorf("failed to verify launcher script %s", scriptPath)
+	}
+
+	systemDir, err := windows.GetSystemDirectory()
+	if err != nil {
+		os.Remove(scriptPath)
+		return fmt.Errorf("failed to get system directory: %w", err)
+	}
+	command := composeCommand(filepath.Join(systemDir, "wscript.exe"), `"`+scriptPath+`"`)
+
+	// Track the script so removal can clean it up
+	previous, err := GetEntryMetadataTyped(entry.Name, registryType)
+	hadMetadata := err == nil
+	if err != nil && !errors.Is(err, ErrNoMetadata) {
+		os.Remove(scriptPath)
+		return err
+	}
+	metadata := previous
+	metadata.Launcher = scriptPath
+	if err := SetEntryMetadata(entry.Name, registryType, metadata); err != nil {
+		os.Remove(scriptPath)
+		return err
+	}
+
+	if err := writeStringValue(entry.Name, command, registry.SZ, registryType); err != nil {
+		// Leave neither the script nor metadata pointing at it behind
+		os.Remove(scriptPath)
+		if hadMetadata {
+			_ = SetEntryMetadata(entry.Name, registryType, previous)
+		} else {
+			_ = deleteEntryMetadata(entry.Name, registryType)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// launcherDir returns the directory generated launcher scripts are stored in.
+// Scripts for the all-users locations go under Program Files rather than
+// ProgramData, where standard users may create and change files
+func launcherDir(registryType StartupRegistryType) (string, error) {
+	folder := windows.FOLDERID_LocalAppData
+	if _, rootKey := getRegistryPath(registryType); rootKey != registry.CURRENT_USER {
+		folder = windows.FOLDERID_ProgramFiles
+	}
+
+	base, err := windows.KnownFolderPath(folder, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate launcher directory: %w", err)
+	}
+
+	return filepath.Join(base, "winstartupreg", "launchers"), nil
+}
+
+// removeLauncher deletes the launcher script generated for an entry, if any. A
+// script that another entry still uses, such as the original and a copy made by
+// CopyStartupEntry, is kept until the last of them is removed
+func removeLauncher(name string, registryType StartupRegistryType) error {
+	metadata, err := GetEntryMetadataTyped(name, registryType)
+	if err != nil || metadata.Launcher == "" {
+		return nil
+	}
+
+	if launcherInUse(metadata.Launcher, name, registryType) {
+		return nil
+	}
+	if err := os.Remove(metadata.Launcher); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove launcher script: %w", err)
+	}
+
+	return nil
+}
+
+// launcherInUse reports whether an entry other than name in registryType still
+// references the launcher script at path in its metadata
+func launcherInUse(path, name string, registryType StartupRegistryType) bool {
+	// Launchers are only generated for these locations
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	for _, location := range registryTypes {
+		keyPath, rootKey := getRegistryPath(location)
+		k, err := registry.OpenKey(rootKey, keyPath+`\`+metadataKeyName, registry.ENUMERATE_SUB_KEYS)
+		if err != nil {
+			continue
+		}
+		names, err := k.ReadSubKeyNames(0)
+		k.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, other := range names {
+			if location == registryType && other == name {
+				continue
+			}
+			metadata, err := GetEntryMetadataTyped(other, location)
+			if err == nil && strings.EqualFold(metadata.Launcher, path) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+)
+
+// RepairMalformedCommands finds entries whose command has its executable wrapped
+// in doubled quotes (""C:\app.exe"") or ends in stray spaces or semicolons, and
+// rewrites them with single quotes and the garbage trimmed. Arguments are left
+// as they are. Every change is reported; with dryRun nothing is written. An
+// entry modified by someone else in the meantime is left alone and reported
+// with Applied false
+func RepairMalformedCommands(dryRun bool) ([]Change, error) {
+	if !dryRun {
+		if err := checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var changes []Change
+	var errs []error
+	for _, registryType := range registryTypes {
+		items, err := listStartupItems(registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, item := range items {
+			repaired, reason, ok := repairMalformedCommand(item.Command)
+			if !ok {
+				continue
+			}
+
+			change := Change{
+				Location:   registryType,
+				Name:       item.Name,
+				OldCommand: item.Command,
+				NewCommand: repaired,
+				Reason:     reason,
+			}
+			if !dryRun {
+				swapped, err := CompareAndSwap(item.Name, item.Command, repaired, registryType)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				change.Applied = swapped
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
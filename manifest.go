@@ -0,0 +1,56 @@
+package winstartupreg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"unicode/utf16"
+)
+
+// manifestExecutionLevel returns the requestedExecutionLevel of an application
+// manifest, or "" when the manifest does not request one
+func manifestExecutionLevel(manifest []byte) (string, error) {
+	manifest = decodeUTF16BOM(manifest)
+
+	decoder := xml.NewDecoder(bytes.NewReader(manifest))
+	// Text is UTF-8 at this point whatever the declaration claims
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != "requestedExecutionLevel" {
+			continue
+		}
+		for _, attr := range element.Attr {
+			if attr.Name.Local == "level" {
+				return attr.Value, nil
+			}
+		}
+	}
+}
+
+// decodeUTF16BOM converts UTF-16LE text that starts with a byte order mark to UTF-8.
+// Anything else is returned unchanged
+func decodeUTF16BOM(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xFE {
+		return data
+	}
+
+	units := make([]uint16, 0, len(data)/2)
+	for i := 2; i+1 < len(data); i += 2 {
+		units = append(units, uint16(data[i])|uint16(data[i+1])<<8)
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
@@ -0,0 +1,65 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// RequiresElevation reports whether an entry's executable asks for administrator
+// rights through the requestedExecutionLevel of its embedded manifest
+// (requireAdministrator or highestAvailable). Such an entry raises a UAC prompt
+// at every logon, or fails silently when UAC is off. An executable without a
+// manifest runs as the invoker
+func RequiresElevation(entry StartupEntry) (bool, error) {
+	executable := CommandExecutable(entry.Command)
+	if _, err := os.Stat(executable); err != nil {
+		return false, fmt.Errorf("failed to resolve executable: %w", err)
+	}
+
+	manifest, err := readManifest(executable)
+	if err != nil {
+		return false, err
+	}
+	if manifest == nil {
+		return false, nil
+	}
+
+	level, err := manifestExecutionLevel(manifest)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse manifest of %s: %w", executable, err)
+	}
+
+	return level == "requireAdministrator" || level == "highestAvailable", nil
+}
+
+// readManifest returns the application manifest embedded in a PE file as
+// resource RT_MANIFEST #1, or nil when the file has none
+func readManifest(path string) ([]byte, error) {
+	// Map the file as a resource-only image, nothing in it is executed
+	module, err := windows.LoadLibraryEx(path, 0, windows.LOAD_LIBRARY_AS_DATAFILE|windows.LOAD_LIBRARY_AS_IMAGE_RESOURCE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	defer windows.FreeLibrary(module)
+
+	resource, err := windows.FindResource(module, windows.CREATEPROCESS_MANIFEST_RESOURCE_ID, windows.RT_MANIFEST)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_RESOURCE_TYPE_NOT_FOUND) || errors.Is(err, windows.ERROR_RESOURCE_NAME_NOT_FOUND) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find manifest of %s: %w", path, err)
+	}
+
+	data, err := windows.LoadResourceData(module, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest of %s: %w", path, err)
+	}
+
+	// The resource memory goes away with the module
+	return append([]byte(nil), data...), nil
+}
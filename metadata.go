@@ -0,0 +1,100 @@
+package winstartupreg
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// MetadataSchemaVersion is the version of the EntryMetadata format written by this package
+const MetadataSchemaVersion = 1
+
+// ErrNoMetadata is returned when an entry has no sidecar metadata
+var ErrNoMetadata = errors.New("winstartupreg: entry has no metadata")
+
+// EntryMetadata is the sidecar metadata tools can attach to a startup entry. It
+// is stored as JSON in the default value of the __meta\<name> subkey of the
+// entry's location, which Windows ignores when launching startup entries.
+//
+// The format is versioned through SchemaVersion. Fields this version does not
+// know about are kept in Extra and written back unchanged, so tools built on
+// different versions of the schema do not clobber each other's data
+type EntryMetadata struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Owner         string    `json:"owner,omitempty"`       // Tool or product that manages the entry
+	DisplayName   string    `json:"displayName,omitempty"` // Human friendly name of the entry
+	CreatedAt     time.Time `json:"createdAt"`             // When the entry was first registered
+	Launcher      string    `json:"launcher,omitempty"`    // Launcher script generated for the entry
+	Pinned        bool      `json:"pinned,omitempty"`      // Protected from removal without WithForce
+
+	// Extra holds fields written by other schema versions, keyed by JSON name
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// entryMetadataFields is EntryMetadata without its methods, to avoid recursion in the JSON methods
+type entryMetadataFields EntryMetadata
+
+// MarshalJSON encodes the known fields together with the preserved unknown ones
+func (m EntryMetadata) MarshalJSON() ([]byte, error) {
+	known, err := json.Marshal(entryMetadataFields(m))
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Extra) == 0 {
+		return known, nil
+	}
+
+	merged := make(map[string]json.RawMessage, len(m.Extra))
+	for key, value := range m.Extra {
+		merged[key] = value
+	}
+	// Known fields win over stale copies in Extra
+	var knownFields map[string]json.RawMessage
+	if err := json.Unmarshal(known, &knownFields); err != nil {
+		return nil, err
+	}
+	for key, value := range knownFields {
+		merged[key] = value
+	}
+
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON decodes the known fields and keeps every other field in Extra
+func (m *EntryMetadata) UnmarshalJSON(data []byte) error {
+	var fields entryMetadataFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, key := range knownMetadataFields() {
+		delete(all, key)
+	}
+	if len(all) > 0 {
+		fields.Extra = all
+	}
+
+	*m = EntryMetadata(fields)
+	return nil
+}
+
+// knownMetadataFields returns the JSON names of the fields EntryMetadata declares
+func knownMetadataFields() []string {
+	t := reflect.TypeOf(entryMetadataFields{})
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
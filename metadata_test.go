@@ -0,0 +1,27 @@
+package winstartupreg_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+var _ = Describe("Entry Metadata Format", func() {
+	It("Should preserve fields from other schema versions on rewrite", func() {
+		stored := `{"schemaVersion":2,"owner":"agent","createdAt":"2024-01-02T03:04:05Z","channel":"beta"}`
+
+		var metadata winstartupreg.EntryMetadata
+		Expect(json.Unmarshal([]byte(stored), &metadata)).To(Succeed())
+		Expect(metadata.SchemaVersion).To(Equal(2))
+		Expect(metadata.Owner).To(Equal("agent"))
+		Expect(metadata.Extra).To(HaveKeyWithValue("channel", json.RawMessage(`"beta"`)))
+
+		metadata.DisplayName = "Agent"
+		rewritten, err := json.Marshal(metadata)
+		Expect(err).To(BeNil())
+		Expect(rewritten).To(MatchJSON(`{"schemaVersion":2,"owner":"agent","displayName":"Agent","createdAt":"2024-01-02T03:04:05Z","channel":"beta"}`))
+	})
+})
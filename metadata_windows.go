@@ -0,0 +1,157 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// metadataKeyName is the subkey of a startup location that holds entry metadata
+const metadataKeyName = "__meta"
+
+// getMetadataPath returns the key that holds the metadata of an entry
+func getMetadataPath(name string, registryType StartupRegistryType) (string, registry.Key, error) {
+	if name == "" || strings.Contains(name, `\`) {
+		return "", 0, fmt.Errorf("entry name '%s' cannot carry metadata", name)
+	}
+
+	keyPath, rootKey := getRegistryPath(registryType)
+	return keyPath + `\` + metadataKeyName + `\` + name, rootKey, nil
+}
+
+// GetEntryMetadataTyped reads the sidecar metadata of an entry. It returns
+// ErrNoMetadata when the entry has none
+func GetEntryMetadataTyped(name string, registryType StartupRegistryType) (EntryMetadata, error) {
+	keyPath, rootKey, err := getMetadataPath(name, registryType)
+	if err != nil {
+		return EntryMetadata{}, err
+	}
+
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return EntryMetadata{}, ErrNoMetadata
+		}
+		return EntryMetadata{}, fmt.Errorf("failed to open metadata key: %w", err)
+	}
+	defer k.Close()
+
+	data, _, err := k.GetStringValue("")
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return EntryMetadata{}, ErrNoMetadata
+		}
+		return EntryMetadata{}, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var metadata EntryMetadata
+	if err := json.Unmarshal([]byte(data), &metadata); err != nil {
+		return EntryMetadata{}, fmt.Errorf("failed to decode metadata of '%s': %w", name, err)
+	}
+
+	return metadata, nil
+}
+
+// SetEntryMetadata stores the sidecar metadata of an entry. Unknown fields read
+// into metadata.Extra are written back unchanged, and SchemaVersion is raised to
+// MetadataSchemaVersion but never lowered
+func SetEntryMetadata(name string, registryType StartupRegistryType, metadata EntryMetadata) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	keyPath, rootKey, err := getMetadataPath(name, registryType)
+	if err != nil {
+		return err
+	}
+
+	if metadata.SchemaVersion < MetadataSchemaVersion {
+		metadata.SchemaVersion = MetadataSchemaVersion
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	k, _, err := registry.CreateKey(rootKey, keyPath, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open metadata key: %w", err)
+	}
+	defer k.Close()
+
+	if err := k.SetStringValue("", string(data)); err != nil {
+		return fmt.Errorf("failed to set metadata: %w", err)
+	}
+
+	return nil
+}
+
+// deleteEntryMetadata removes the metadata of an entry. Missing metadata is not an error
+func deleteEntryMetadata(name string, registryType StartupRegistryType) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	keyPath, rootKey, err := getMetadataPath(name, registryType)
+	if err != nil {
+		return nil
+	}
+
+	if err := registry.DeleteKey(rootKey, keyPath); err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("failed to delete metadata: %w", err)
+	}
+
+	return nil
+}
+
+// PinEntry protects an existing entry from removal: RemoveStartupEntry and
+// SafeRemoveStartupEntry refuse to delete it with ErrEntryPinned unless
+// WithForce is passed. The flag is kept in the entry's metadata
+func PinEntry(name string, registryType StartupRegistryType) error {
+	return setEntryPinned(name, registryType, true)
+}
+
+// UnpinEntry lifts the protection set by PinEntry
+func UnpinEntry(name string, registryType StartupRegistryType) error {
+	return setEntryPinned(name, registryType, false)
+}
+
+// setEntryPinned updates the pinned flag in the metadata of an entry, keeping its other fields
+func setEntryPinned(name string, registryType StartupRegistryType, pinned bool) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	// Only entries that exist can be pinned
+	present, err := EntryExists(name, registryType)
+	if err != nil {
+		return err
+	}
+	if !present {
+		keyPath, _ := getRegistryPath(registryType)
+		return fmt.Errorf("startup entry '%s' not found in %s: %w", name, keyPath, ErrEntryNotFound)
+	}
+
+	metadata, err := GetEntryMetadataTyped(name, registryType)
+	switch {
+	case errors.Is(err, ErrNoMetadata):
+		if !pinned {
+			return nil
+		}
+		metadata = EntryMetadata{CreatedAt: time.Now().UTC()}
+	case err != nil:
+		return err
+	}
+	if metadata.Pinned == pinned {
+		return nil
+	}
+
+	metadata.Pinned = pinned
+	return SetEntryMetadata(name, registryType, metadata)
+}
@@ -0,0 +1,254 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// numberedValue is a value of a numbered location together with its data
+type numberedValue struct {
+	name    string
+	valType uint32
+	data    string
+}
+
+// isNumberedLocation reports whether value names of a location are launch ordinals
+func isNumberedLocation(registryType StartupRegistryType) bool {
+	return registryType == CurrentUserPolicyRun || registryType == AllUsersPolicyRun
+}
+
+// AddStartupEntryAt inserts an entry at the given one-based ordinal of a numbered
+// location such as CurrentUserPolicyRun. Entries at or after the ordinal are shifted
+// up by one so the sequence stays contiguous. The value name of a numbered location
+// is its ordinal, so entry.Name is not stored
+func AddStartupEntryAt(entry StartupEntry, index int, registryType StartupRegistryType) error {
+	if !isNumberedLocation(registryType) {
+		return fmt.Errorf("location %s does not use numbered value names", registryType)
+	}
+	if index < 1 {
+		return fmt.Errorf("ordinal must be 1 or greater, got %d", index)
+	}
+
+	// Validate input, the value is stored under its ordinal
+	if err := (StartupEntry{Name: strconv.Itoa(index), Command: entry.Command, Args: entry.Args}).Validate(); err != nil {
+		return err
+	}
+
+	// Normalize and validate command path
+	fullPath, err := resolveCommand(entry.Command)
+	if err != nil {
+		return err
+	}
+
+	k, err := openNumberedKey(registryType)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	values, err := readNumberedValues(k)
+	if err != nil {
+		return err
+	}
+
+	// Insert the new value, appending when the ordinal is past the end
+	if index > len(values) {
+		index = len(values) + 1
+	}
+	inserted := numberedValue{valType: registry.SZ, data: entryCommandLine(fullPath, fullPath, entry.Args)}
+	values = append(values[:index-1], append([]numberedValue{inserted}, values[index-1:]...)...)
+
+	return writeNumberedValues(k, registryType, values)
+}
+
+// ReorderStartupEntries renumbers the values of a numbered location so that the
+// value names listed in order come first, in that order, followed by any values
+// that were not listed in their existing order
+func ReorderStartupEntries(registryType StartupRegistryType, order []string) error {
+	if !isNumberedLocation(registryType) {
+		return fmt.Errorf("location %s does not use numbered value names", registryType)
+	}
+
+	k, err := openNumberedKey(registryType)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	values, err := readNumberedValues(k)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]numberedValue, len(values))
+	for _, value := range values {
+		byName[value.name] = value
+	}
+
+	// Listed values first, each exactly once
+	reordered := make([]numberedValue, 0, len(values))
+	for _, name := range order {
+		value, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("startup entry '%s' not found or listed twice", name)
+		}
+		reordered = append(reordered, value)
+		delete(byName, name)
+	}
+
+	// Then the remaining values in their current order
+	for _, value := range values {
+		if _, ok := byName[value.name]; ok {
+			reordered = append(reordered, value)
+		}
+	}
+
+	return writeNumberedValues(k, registryType, reordered)
+}
+
+// openNumberedKey opens (creating if needed) the key of a numbered location for writing
+func openNumberedKey(registryType StartupRegistryType) (registry.Key, error) {
+	if err := checkWritable(); err != nil {
+		return 0, err
+	}
+	if err := requireElevation(registryType); err != nil {
+		return 0, err
+	}
+
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Renumbering reads every value before rewriting them
+	k, _, err := registry.CreateKey(rootKey, keyPath, registry.QUERY_VALUE|registry.SET_VALUE)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+
+	return k, nil
+}
+
+// readNumberedValues returns the values with a numeric name sorted by that number.
+// Values with other names are ignored and left untouched
+func readNumberedValues(k registry.Key) ([]numberedValue, error) {
+	valueNames, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value names: %w", err)
+	}
+
+	var values []numberedValue
+	for _, name := range valueNames {
+		if n, err := strconv.Atoi(name); err != nil || n < 1 {
+			continue
+		}
+
+		data, valType, err := k.GetStringValue(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry value '%s': %w", name, err)
+		}
+		values = append(values, numberedValue{name: name, valType: valType, data: data})
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		a, _ := strconv.Atoi(values[i].name)
+		b, _ := strconv.Atoi(values[j].name)
+		return a < b
+	})
+
+	return values, nil
+}
+
+// writeNumberedValues stores values as the contiguous sequence "1".."n" and
+// deletes any numbered value left over beyond the end of the sequence. If a
+// write fails partway the previous values are put back and the new ordinals
+// removed again, so the key never ends up with duplicates or gaps. Every ordinal
+// whose command changes is recorded in the journal once all writes succeeded
+func writeNumberedValues(k registry.Key, registryType StartupRegistryType, values []numberedValue) error {
+	existing, err := readNumberedValues(k)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]string, len(existing))
+	for _, value := range existing {
+		previous[value.name] = value.data
+	}
+
+	// Open the journal before anything changes so a required journal can stop the write
+	journal, err := openJournal()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	var records []JournalEntry
+	for i, value := range values {
+		name := strconv.Itoa(i + 1)
+		before, existed := previous[name]
+
+		if err := setNumberedValue(k, name, value); err != nil {
+			return restoreNumberedValues(k, existing, i, fmt.Errorf("failed to set registry value '%s': %w", name, err))
+		}
+
+		if !existed || before != value.data {
+			records = append(records, JournalEntry{Operation: JournalAdd, Location: registryType, Name: name, Before: before, After: value.data})
+		}
+	}
+
+	for _, value := range existing {
+		n, _ := strconv.Atoi(value.name)
+		if n > len(values) || value.name != strconv.Itoa(n) {
+			if err := k.DeleteValue(value.name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+				return restoreNumberedValues(k, existing, len(values), fmt.Errorf("failed to delete registry value '%s': %w", value.name, err))
+			}
+			records = append(records, JournalEntry{Operation: JournalRemove, Location: registryType, Name: value.name, Before: value.data})
+		}
+	}
+
+	var journalErr error
+	for _, record := range records {
+		if err := journal.record(record); err != nil && journalErr == nil {
+			journalErr = err
+		}
+	}
+	return journalErr
+}
+
+// setNumberedValue stores value under name, keeping its value type
+func setNumberedValue(k registry.Key, name string, value numberedValue) error {
+	if value.valType == registry.EXPAND_SZ {
+		return k.SetExpandStringValue(name, value.data)
+	}
+	return k.SetStringValue(name, value.data)
+}
+
+// restoreNumberedValues undoes a failed writeNumberedValues: it writes back the
+// existing values and deletes the ordinals "1".."written" that none of them
+// used. It returns cause, joined with anything that failed while restoring
+func restoreNumberedValues(k registry.Key, existing []numberedValue, written int, cause error) error {
+	used := make(map[string]bool, len(existing))
+	var errs []error
+	for _, value := range existing {
+		used[value.name] = true
+		if err := setNumberedValue(k, value.name, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := 1; i <= written; i++ {
+		name := strconv.Itoa(i)
+		if used[name] {
+			continue
+		}
+		if err := k.DeleteValue(name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w, and failed to restore the previous values: %w", cause, err)
+	}
+	return fmt.Errorf("%w, previous values restored", cause)
+}
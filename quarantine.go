@@ -0,0 +1,90 @@
+package winstartupreg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QuarantinedEntry is the full definition of a startup entry captured by QuarantineToFile
+type QuarantinedEntry struct {
+	Name      string              `json:"name"`
+	Command   string              `json:"command"`
+	ValueType uint32              `json:"valueType"`
+	Location  StartupRegistryType `json:"location"`
+	Enabled   bool                `json:"enabled"`
+	Approval  []byte              `json:"approval,omitempty"` // Raw StartupApproved blob, if any
+	Metadata  *EntryMetadata      `json:"metadata,omitempty"` // Sidecar metadata, if any, including the launcher script kept on disk
+}
+
+// quarantineFile is the on-disk format of a quarantine file
+type quarantineFile struct {
+	Version    int                `json:"version"`
+	CapturedAt time.Time          `json:"capturedAt"`
+	Entries    []QuarantinedEntry `json:"entries"`
+}
+
+const quarantineFileVersion = 1
+
+// writeQuarantineFile stores entries at path without ever leaving a partial file behind
+func writeQuarantineFile(path string, entries []QuarantinedEntry) error {
+	data, err := json.MarshalIndent(quarantineFile{
+		Version:    quarantineFileVersion,
+		CapturedAt: time.Now().UTC(),
+		Entries:    entries,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode quarantine file: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic stores data at path. The data is written to a temporary file
+// in the same directory, flushed to disk and then renamed over path, so a
+// failure never leaves a partial file behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// readQuarantineFile loads the entries stored by writeQuarantineFile
+func readQuarantineFile(path string) ([]QuarantinedEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine file: %w", err)
+	}
+
+	var file quarantineFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode quarantine file: %w", err)
+	}
+	if file.Version != quarantineFileVersion {
+		return nil, fmt.Errorf("unsupported quarantine file version %d", file.Version)
+	}
+
+	return file.Entries, nil
+}
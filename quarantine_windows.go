@@ -0,0 +1,206 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// QuarantineToFile captures the full definition of each named entry (name,
+// command, value type, location, approval state and metadata) from every known location
+// into a file at path, then removes the entries from the registry. Nothing is
+// removed unless every entry was found, none of them is pinned and the file was
+// written successfully. Launcher scripts of AddNoWindowStartupEntry entries stay
+// on disk for the restore
+func QuarantineToFile(names []string, path string) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	// Capture everything first
+	var captured []QuarantinedEntry
+	for _, name := range names {
+		found := false
+		for _, registryType := range registryTypes {
+			present, err := EntryExists(name, registryType)
+			if err != nil {
+				return err
+			}
+			if !present {
+				continue
+			}
+
+			entry, err := captureEntry(name, registryType)
+			if err != nil {
+				return err
+			}
+			// A pinned entry would stay behind, quarantine nothing instead
+			if entry.Metadata != nil && entry.Metadata.Pinned {
+				return fmt.Errorf("startup entry '%s' in %s: %w", name, registryType, ErrEntryPinned)
+			}
+			captured = append(captured, entry)
+			found = true
+		}
+
+		if !found {
+			return fmt.Errorf("startup entry '%s' not found in any location: %w", name, ErrEntryNotFound)
+		}
+	}
+
+	// Only remove once the capture is safely on disk
+	if err := writeQuarantineFile(path, captured); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range captured {
+		// The launcher script stays so a restored entry still finds it
+		if err := RemoveStartupEntry(entry.Name, entry.Location, keepLauncher()); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := deleteApprovalBlob(entry.Name, entry.Location); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RestoreFromQuarantineFile writes back every entry captured by QuarantineToFile,
+// including its original value type, approval state and metadata
+func RestoreFromQuarantineFile(path string) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	entries, err := readQuarantineFile(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if err := writeStringValue(entry.Name, entry.Command, entry.ValueType, entry.Location); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(entry.Approval) > 0 {
+			if err := writeApprovalBlob(entry.Name, entry.Location, entry.Approval); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if entry.Metadata != nil {
+			if err := SetEntryMetadata(entry.Name, entry.Location, *entry.Metadata); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// captureEntry reads the definition of an entry that is known to exist,
+// including its approval state and metadata
+func captureEntry(name string, registryType StartupRegistryType) (QuarantinedEntry, error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key with read access
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return QuarantinedEntry{}, fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	command, valType, err := k.GetStringValue(name)
+	if err != nil {
+		return QuarantinedEntry{}, fmt.Errorf("failed to read startup entry '%s' in %s: %w", name, registryType, classifyRegistryError(err, ErrEntryNotFound))
+	}
+
+	approval, err := readApprovalBlob(name, registryType)
+	if err != nil {
+		return QuarantinedEntry{}, err
+	}
+
+	// Unreadable metadata is an error, it may hold a pin
+	var captured *EntryMetadata
+	metadata, err := GetEntryMetadataTyped(name, registryType)
+	if err == nil {
+		captured = &metadata
+	} else if !errors.Is(err, ErrNoMetadata) {
+		return QuarantinedEntry{}, err
+	}
+
+	return QuarantinedEntry{
+		Name:      name,
+		Command:   command,
+		ValueType: valType,
+		Location:  registryType,
+		Enabled:   approvalBlobEnabled(approval),
+		Approval:  approval,
+		Metadata:  captured,
+	}, nil
+}
+
+// writeStringValue stores a REG_SZ or REG_EXPAND_SZ value in a startup location,
+// creating the key if it does not exist yet
+func writeStringValue(name, data string, valType uint32, registryType StartupRegistryType) error {
+	return writeStringValueInView(name, data, valType, registryType, DefaultView)
+}
+
+// writeStringValueInView is writeStringValue through the given registry view. The
+// write is recorded in the journal as an add
+func writeStringValueInView(name, data string, valType uint32, registryType StartupRegistryType, view RegistryView) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if err := requireElevation(registryType); err != nil {
+		return err
+	}
+
+	// Open the journal before anything changes so a required journal can stop the write
+	journal, err := openJournal()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	var before string
+	if journal != nil {
+		before = currentCommand(name, registryType, view)
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	k, _, err := registry.CreateKey(rootKey, keyPath, registry.SET_VALUE|uint32(view))
+	if err != nil {
+		return fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	switch valType {
+	case registry.SZ:
+		err = k.SetStringValue(name, data)
+	case registry.EXPAND_SZ:
+		err = k.SetExpandStringValue(name, data)
+	default:
+		return fmt.Errorf("unsupported value type %d for startup entry '%s'", valType, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set registry value: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+
+	return journal.record(JournalEntry{Operation: JournalAdd, Location: registryType, Name: name, Before: before, After: data})
+}
@@ -0,0 +1,338 @@
+package winstartupreg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Registry value types as they appear in .reg files
+const (
+	valueTypeSZ       = 1
+	valueTypeExpandSZ = 2
+)
+
+// regFileKeys maps the full key names used in .reg files to the startup locations
+var regFileKeys = map[StartupRegistryType]string{
+	CurrentUserRun:       `HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Run`,
+	CurrentUserRunOnce:   `HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\RunOnce`,
+	AllUsersRun:          `HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows\CurrentVersion\Run`,
+	AllUsersRunOnce:      `HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnce`,
+	CurrentUserPolicyRun: `HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`,
+	AllUsersPolicyRun:    `HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`,
+}
+
+// regFileOp is one change a .reg file makes to a startup location, in file order
+type regFileOp struct {
+	Location  StartupRegistryType
+	DeleteKey bool   // [-key]: the whole location is removed
+	Name      string // Value name, empty for the default value
+	Delete    bool   // "name"=-
+	Value     entryValue
+}
+
+// parseRegFile reads a .reg export (UTF-16 "Windows Registry Editor Version 5.00"
+// or ANSI "REGEDIT4") and returns the changes it makes to the startup locations.
+// Sections for other keys are ignored, as are values that are not strings. An
+// expand string (hex(2):) is decoded to its unexpanded text so it compares equal
+// to what the registry stores
+func parseRegFile(r io.Reader) ([]regFileOp, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .reg file: %w", err)
+	}
+
+	text, err := decodeRegFileText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ops      []regFileOp
+		lines    []string
+		header   string
+		location StartupRegistryType
+		inRun    bool
+	)
+
+	// Join hex continuation lines first, a trailing backslash never ends a string value
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(nil, 1<<20)
+	var pending strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `"`) && strings.Contains(pending.String()+line, "=hex") {
+			pending.WriteString(strings.TrimSuffix(line, `\`))
+			continue
+		}
+		pending.WriteString(line)
+		lines = append(lines, pending.String())
+		pending.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read .reg file: %w", err)
+	}
+	if pending.Len() > 0 {
+		lines = append(lines, pending.String())
+	}
+
+	for i, line := range lines {
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if header == "" {
+			if line != "Windows Registry Editor Version 5.00" && line != "REGEDIT4" {
+				return nil, fmt.Errorf("not a .reg file: unexpected header %q", line)
+			}
+			header = line
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed key %q", i+1, line)
+			}
+			key := line[1 : len(line)-1]
+			deleteKey := strings.HasPrefix(key, "-")
+			key = expandRootKey(strings.TrimPrefix(key, "-"))
+
+			inRun = false
+			for loc, name := range regFileKeys {
+				switch {
+				case strings.EqualFold(key, name):
+					location, inRun = loc, !deleteKey
+					if deleteKey {
+						ops = append(ops, regFileOp{Location: loc, DeleteKey: true})
+					}
+				case deleteKey && len(name) > len(key) && strings.EqualFold(name[:len(key)+1], key+`\`):
+					// Deleting a parent key removes the location along with it
+					ops = append(ops, regFileOp{Location: loc, DeleteKey: true})
+				}
+			}
+			continue
+		}
+
+		if !inRun {
+			continue
+		}
+
+		op, ok, err := parseRegFileValue(line, header == "REGEDIT4")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if ok {
+			op.Location = location
+			ops = append(ops, op)
+		}
+	}
+
+	if header == "" {
+		return nil, fmt.Errorf("not a .reg file: missing header")
+	}
+
+	return ops, nil
+}
+
+// decodeRegFileText converts the raw bytes of a .reg file to text. Files written
+// by regedit are UTF-16LE with a byte order mark, REGEDIT4 files are ANSI
+func decodeRegFileText(data []byte) (string, error) {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) {
+		text, err := decodeUTF16LE(data[2:])
+		if err != nil {
+			return "", fmt.Errorf("invalid .reg file encoding: %w", err)
+		}
+		return text, nil
+	}
+	return string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})), nil
+}
+
+// decodeUTF16LE decodes little-endian UTF-16 bytes
+func decodeUTF16LE(data []byte) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("odd number of bytes in UTF-16 data")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// expandRootKey replaces an abbreviated root key such as HKCU with its full name
+func expandRootKey(key string) string {
+	root, rest, _ := strings.Cut(key, `\`)
+	switch strings.ToUpper(root) {
+	case "HKCU":
+		root = "HKEY_CURRENT_USER"
+	case "HKLM":
+		root = "HKEY_LOCAL_MACHINE"
+	}
+	return root + `\` + rest
+}
+
+// parseRegFileValue parses a "name"=data line. It reports false for values that
+// are not strings, which startup locations ignore
+func parseRegFileValue(line string, ansi bool) (regFileOp, bool, error) {
+	var op regFileOp
+
+	// The value name is @ for the default value or a quoted string
+	var rest string
+	if strings.HasPrefix(line, "@") {
+		rest = line[1:]
+	} else {
+		name, n, err := parseRegFileString(line)
+		if err != nil {
+			return op, false, err
+		}
+		op.Name, rest = name, line[n:]
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return op, false, fmt.Errorf("missing '=' after value name")
+	}
+	rest = strings.TrimSpace(rest[1:])
+
+	switch {
+	case rest == "-":
+		op.Delete = true
+	case strings.HasPrefix(rest, `"`):
+		data, n, err := parseRegFileString(rest)
+		if err != nil {
+			return op, false, err
+		}
+		if strings.TrimSpace(rest[n:]) != "" {
+			return op, false, fmt.Errorf("unexpected data after string value")
+		}
+		op.Value = entryValue{Command: data, ValueType: valueTypeSZ}
+	case strings.HasPrefix(strings.ToLower(rest), "hex(1):"), strings.HasPrefix(strings.ToLower(rest), "hex(2):"):
+		data, err := decodeRegFileHexString(rest[7:], ansi)
+		if err != nil {
+			return op, false, err
+		}
+		op.Value = entryValue{Command: data, ValueType: valueTypeSZ}
+		if rest[4] == '2' {
+			op.Value.ValueType = valueTypeExpandSZ
+		}
+	default:
+		return op, false, nil
+	}
+
+	return op, true, nil
+}
+
+// parseRegFileString parses the quoted string at the start of s, undoing the \\
+// and \" escapes, and returns it together with the number of bytes consumed
+func parseRegFileString(s string) (string, int, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", 0, fmt.Errorf("expected a quoted string")
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+		}
+		b.WriteByte(s[i])
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// decodeRegFileHexString decodes the comma-separated bytes of a hex(1) or hex(2)
+// value. Version 5 files store the string as UTF-16LE, REGEDIT4 files as ANSI.
+// The data normally ends with a NUL terminator, anything after the first NUL is
+// not part of the string, just as the registry API would report it
+func decodeRegFileHexString(s string, ansi bool) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "", nil
+	}
+
+	var data []byte
+	for _, part := range strings.Split(strings.TrimSuffix(s, ","), ",") {
+		b, err := hex.DecodeString(part)
+		if err != nil || len(b) != 1 {
+			return "", fmt.Errorf("invalid hex byte %q", part)
+		}
+		data = append(data, b[0])
+	}
+
+	if ansi {
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		return string(data), nil
+	}
+
+	// A dangling odd byte can only be a truncated terminator
+	if len(data)%2 != 0 {
+		data = data[:len(data)-1]
+	}
+	text, err := decodeUTF16LE(data)
+	if err != nil {
+		return "", err
+	}
+	if i := strings.IndexByte(text, 0); i >= 0 {
+		text = text[:i]
+	}
+	return text, nil
+}
+
+// applyRegFile returns the state of the startup locations after importing ops
+// on top of live, which is left untouched. Value names are matched ignoring
+// case, as the registry does, and an existing value keeps the name it is
+// stored under
+func applyRegFile(live map[StartupRegistryType]map[string]entryValue, ops []regFileOp) map[StartupRegistryType]map[string]entryValue {
+	after := make(map[StartupRegistryType]map[string]entryValue)
+	names := make(map[StartupRegistryType]map[string]string)
+	for location, entries := range live {
+		after[location] = make(map[string]entryValue)
+		names[location] = make(map[string]string)
+		for name, value := range entries {
+			after[location][name] = value
+			names[location][strings.ToLower(name)] = name
+		}
+	}
+
+	for _, op := range ops {
+		if after[op.Location] == nil || op.DeleteKey {
+			after[op.Location] = make(map[string]entryValue)
+			names[op.Location] = make(map[string]string)
+		}
+
+		key := strings.ToLower(op.Name)
+		name, ok := names[op.Location][key]
+		switch {
+		case op.DeleteKey:
+		case op.Delete:
+			if ok {
+				delete(after[op.Location], name)
+				delete(names[op.Location], key)
+			}
+		default:
+			if !ok {
+				name = op.Name
+				names[op.Location][key] = name
+			}
+			after[op.Location][name] = op.Value
+		}
+	}
+
+	return after
+}
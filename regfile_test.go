@@ -0,0 +1,129 @@
+package winstartupreg_test
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+// utf16RegFile encodes text the way regedit exports it: UTF-16LE with a byte order mark
+func utf16RegFile(text string) []byte {
+	data := []byte{0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+	return data
+}
+
+const (
+	regTypeSZ       = 1
+	regTypeExpandSZ = 2
+)
+
+var _ = Describe("Parsing .reg Files", func() {
+	It("Should read a UTF-16 version 5 export with continued hex(2) data", func() {
+		text := "Windows Registry Editor Version 5.00\r\n" +
+			"\r\n" +
+			"[HKEY_CURRENT_USER\\Software\\Microsoft\\Windows\\CurrentVersion\\Run]\r\n" +
+			"\"Agent\"=hex(2):25,00,53,00,79,00,73,00,74,00,65,00,6d,00,52,00,6f,00,6f,00,\\\r\n" +
+			"  74,00,25,00,5c,00,61,00,67,00,65,00,6e,00,74,00,2e,00,65,00,78,00,65,00,20,\\\r\n" +
+			"  00,2f,00,71,00,00,00\r\n" +
+			"\"Tray\"=\"\\\"C:\\\\Program Files\\\\Tray\\\\tray.exe\\\" --minimized\"\r\n" +
+			"\"Old\"=-\r\n" +
+			"\"Size\"=dword:00000001\r\n"
+
+		ops, err := winstartupreg.ParseRegFile(strings.NewReader(string(utf16RegFile(text))))
+		Expect(err).To(BeNil())
+		Expect(ops).To(Equal([]winstartupreg.RegFileOp{
+			{Location: winstartupreg.CurrentUserRun, Name: "Agent", Value: winstartupreg.EntryValue{Command: `%SystemRoot%\agent.exe /q`, ValueType: regTypeExpandSZ}},
+			{Location: winstartupreg.CurrentUserRun, Name: "Tray", Value: winstartupreg.EntryValue{Command: `"C:\Program Files\Tray\tray.exe" --minimized`, ValueType: regTypeSZ}},
+			{Location: winstartupreg.CurrentUserRun, Name: "Old", Delete: true},
+		}))
+	})
+
+	It("Should decode hex(2) data of a REGEDIT4 file as ANSI", func() {
+		text := "REGEDIT4\r\n" +
+			"\r\n" +
+			"[HKEY_LOCAL_MACHINE\\SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\Run]\r\n" +
+			"\"Agent\"=hex(2):25,53,79,73,74,65,6d,52,6f,6f,74,25,5c,61,67,65,6e,74,2e,\\\r\n" +
+			"  65,78,65,20,2f,71,00\r\n"
+
+		ops, err := winstartupreg.ParseRegFile(strings.NewReader(text))
+		Expect(err).To(BeNil())
+		Expect(ops).To(Equal([]winstartupreg.RegFileOp{
+			{Location: winstartupreg.AllUsersRun, Name: "Agent", Value: winstartupreg.EntryValue{Command: `%SystemRoot%\agent.exe /q`, ValueType: regTypeExpandSZ}},
+		}))
+	})
+
+	It("Should not treat a trailing backslash inside a string as a continuation", func() {
+		text := "Windows Registry Editor Version 5.00\r\n" +
+			"[HKCU\\Software\\Microsoft\\Windows\\CurrentVersion\\RunOnce]\r\n" +
+			"\"Dir\"=\"C:\\\\Tools\\\\\"\r\n" +
+			"\"Next\"=\"C:\\\\next.exe\"\r\n"
+
+		ops, err := winstartupreg.ParseRegFile(strings.NewReader(text))
+		Expect(err).To(BeNil())
+		Expect(ops).To(Equal([]winstartupreg.RegFileOp{
+			{Location: winstartupreg.CurrentUserRunOnce, Name: "Dir", Value: winstartupreg.EntryValue{Command: `C:\Tools\`, ValueType: regTypeSZ}},
+			{Location: winstartupreg.CurrentUserRunOnce, Name: "Next", Value: winstartupreg.EntryValue{Command: `C:\next.exe`, ValueType: regTypeSZ}},
+		}))
+	})
+
+	It("Should report deleted keys and ignore other keys", func() {
+		text := "Windows Registry Editor Version 5.00\r\n" +
+			"[HKEY_CURRENT_USER\\Software\\Vendor]\r\n" +
+			"\"Ignored\"=\"C:\\\\ignored.exe\"\r\n" +
+			"[-HKEY_CURRENT_USER\\Software\\Microsoft\\Windows\\CurrentVersion\\Run]\r\n"
+
+		ops, err := winstartupreg.ParseRegFile(strings.NewReader(text))
+		Expect(err).To(BeNil())
+		Expect(ops).To(Equal([]winstartupreg.RegFileOp{
+			{Location: winstartupreg.CurrentUserRun, DeleteKey: true},
+		}))
+	})
+
+	It("Should reject files without a known header", func() {
+		_, err := winstartupreg.ParseRegFile(strings.NewReader("[HKEY_CURRENT_USER\\Software]\r\n"))
+		Expect(err).To(MatchError(ContainSubstring("unexpected header")))
+
+		_, err = winstartupreg.ParseRegFile(strings.NewReader(""))
+		Expect(err).To(MatchError(ContainSubstring("missing header")))
+	})
+
+	It("Should reject malformed hex data", func() {
+		text := "Windows Registry Editor Version 5.00\r\n" +
+			"[HKEY_CURRENT_USER\\Software\\Microsoft\\Windows\\CurrentVersion\\Run]\r\n" +
+			"\"Agent\"=hex(2):25,zz,00\r\n"
+
+		_, err := winstartupreg.ParseRegFile(strings.NewReader(text))
+		Expect(err).To(MatchError(ContainSubstring("line 3")))
+	})
+})
+
+var _ = Describe("Applying .reg Files", func() {
+	It("Should match value names ignoring case and keep the stored name", func() {
+		live := map[winstartupreg.StartupRegistryType]map[string]winstartupreg.EntryValue{
+			winstartupreg.CurrentUserRun: {
+				"OneDrive": {Command: `C:\old\OneDrive.exe`, ValueType: regTypeSZ},
+				"Teams":    {Command: `C:\Teams\teams.exe`, ValueType: regTypeSZ},
+			},
+		}
+		ops := []winstartupreg.RegFileOp{
+			{Location: winstartupreg.CurrentUserRun, Name: "onedrive", Value: winstartupreg.EntryValue{Command: `C:\new\OneDrive.exe`, ValueType: regTypeSZ}},
+			{Location: winstartupreg.CurrentUserRun, Name: "TEAMS", Delete: true},
+			{Location: winstartupreg.CurrentUserRun, Name: "Agent", Value: winstartupreg.EntryValue{Command: `C:\agent.exe`, ValueType: regTypeSZ}},
+			{Location: winstartupreg.CurrentUserRun, Name: "agent", Value: winstartupreg.EntryValue{Command: `C:\agent.exe /q`, ValueType: regTypeSZ}},
+		}
+
+		after := winstartupreg.ApplyRegFile(live, ops)
+		Expect(after[winstartupreg.CurrentUserRun]).To(Equal(map[string]winstartupreg.EntryValue{
+			"OneDrive": {Command: `C:\new\OneDrive.exe`, ValueType: regTypeSZ},
+			"Agent":    {Command: `C:\agent.exe /q`, ValueType: regTypeSZ},
+		}))
+		Expect(live[winstartupreg.CurrentUserRun]).To(HaveKey("Teams"))
+	})
+})
@@ -0,0 +1,40 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"io"
+)
+
+// DiffRegFile compares the Run sections of a .reg export with the live registry
+// and reports what importing the file would add, remove or change, without
+// applying anything. Like an import, values the file does not mention are kept
+// unless it deletes their key. Value names are matched ignoring case, and a
+// live entry is reported under the name it is stored as. Expand strings (hex(2):) are compared by their
+// unexpanded text and value type, so an entry stored as REG_SZ that the file
+// writes as REG_EXPAND_SZ is reported as changed
+func DiffRegFile(r io.Reader) (SnapshotDiff, error) {
+	ops, err := parseRegFile(r)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	// Read the live state of every location the file touches
+	live := make(map[StartupRegistryType]map[string]entryValue)
+	for _, op := range ops {
+		if _, ok := live[op.Location]; ok {
+			continue
+		}
+
+		items, err := listStartupItems(op.Location)
+		if err != nil {
+			return SnapshotDiff{}, err
+		}
+		live[op.Location] = make(map[string]entryValue)
+		for _, item := range items {
+			live[op.Location][item.Name] = entryValue{Command: item.Command, ValueType: item.ValueType}
+		}
+	}
+
+	return diffEntries(live, applyRegFile(live, ops)), nil
+}
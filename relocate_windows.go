@@ -0,0 +1,100 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CopyStartupEntry copies an entry to another location, under newName when it is
+// not empty. The value type, the sidecar metadata and the StartupApproved state
+// travel with it, so the copy keeps its owner, display name, pin and disabled
+// state. Metadata and approval state left at the destination by an earlier entry
+// of the same name are replaced, they no longer describe anything.
+// The launcher script of an entry added with AddNoWindowStartupEntry is shared by
+// both and only deleted once neither uses it. An existing destination entry is
+// not overwritten
+func CopyStartupEntry(name string, from, to StartupRegistryType, newName string) error {
+	return relocateEntry(name, from, to, newName, false)
+}
+
+// MoveStartupEntry moves an entry to another location, under newName when it is
+// not empty, taking its value type, sidecar metadata and StartupApproved state
+// along and removing them from the source. Pinned entries can be moved, the pin moves with them. An
+// existing destination entry is not overwritten
+func MoveStartupEntry(name string, from, to StartupRegistryType, newName string) error {
+	return relocateEntry(name, from, to, newName, true)
+}
+
+// relocateEntry implements CopyStartupEntry and MoveStartupEntry. The destination
+// is written first and rolled back if its metadata or approval state cannot be
+// stored, the source is only removed once the destination is complete
+func relocateEntry(name string, from, to StartupRegistryType, newName string, move bool) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	// Validate input
+	if name == "" {
+		return fmt.Errorf("entry name cannot be empty")
+	}
+	if newName == "" {
+		newName = name
+	}
+	if from == to && newName == name {
+		return fmt.Errorf("source and destination of '%s' are the same", name)
+	}
+
+	source, err := captureEntry(name, from)
+	if err != nil {
+		return err
+	}
+	if err := (StartupEntry{Name: newName, Command: source.Command}).Validate(); err != nil {
+		return err
+	}
+
+	exists, err := EntryExists(newName, to)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("startup entry '%s' already exists in %s", newName, to)
+	}
+
+	if err := writeStringValue(newName, source.Command, source.ValueType, to); err != nil {
+		return err
+	}
+
+	// Metadata left at the destination belongs to no entry, the source's replaces it
+	if source.Metadata != nil {
+		err = SetEntryMetadata(newName, to, *source.Metadata)
+	} else {
+		err = deleteEntryMetadata(newName, to)
+	}
+	// The same goes for the approval state, so a disabled entry stays disabled
+	if err == nil {
+		if _, _, ok := getApprovalPath(to); ok && source.Approval != nil {
+			err = writeApprovalBlob(newName, to, source.Approval)
+		} else {
+			err = deleteApprovalBlob(newName, to)
+		}
+	}
+	if err != nil {
+		rollbackErr := errors.Join(RemoveStartupEntry(newName, to, WithForce()), deleteApprovalBlob(newName, to))
+		if rollbackErr != nil {
+			return fmt.Errorf("failed to copy metadata of '%s' and to roll back: %w", name, errors.Join(err, rollbackErr))
+		}
+		return fmt.Errorf("failed to copy metadata of '%s', rolled back: %w", name, err)
+	}
+
+	if !move {
+		return nil
+	}
+
+	// The destination now references any launcher script, so the removal keeps it
+	if err := RemoveStartupEntry(name, from, WithForce()); err != nil {
+		return err
+	}
+	return deleteApprovalBlob(name, from)
+}
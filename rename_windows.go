@@ -0,0 +1,138 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// RenameStartupEntry changes the value name of an entry within its location,
+// reading the old value, writing it under newName and deleting the old name
+// through a single key handle. The value type, the sidecar metadata and the
+// StartupApproved state are kept, so an entry disabled in Task Manager stays disabled.
+// It returns ErrEntryNotFound when oldName does not exist and refuses to replace
+// an existing newName unless WithOverwrite is passed. A pinned entry is never
+// replaced, but a pinned entry can be renamed and keeps its pin
+func RenameStartupEntry(oldName, newName string, registryType StartupRegistryType, opts ...RenameOption) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if err := requireElevation(registryType); err != nil {
+		return err
+	}
+
+	options := renameOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Validate input
+	if oldName == "" {
+		return fmt.Errorf("entry name cannot be empty")
+	}
+	if oldName == newName {
+		return fmt.Errorf("old and new name of '%s' are the same", oldName)
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key once for the reads, the write and the delete
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE|registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	command, valType, err := k.GetStringValue(oldName)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return fmt.Errorf("startup entry '%s' not found in %s: %w", oldName, keyPath, ErrEntryNotFound)
+		}
+		return fmt.Errorf("failed to read startup entry '%s' in %s: %w", oldName, registryType, err)
+	}
+	if err := (StartupEntry{Name: newName, Command: command}).Validate(); err != nil {
+		return err
+	}
+
+	before, _, err := k.GetStringValue(newName)
+	replaced := err == nil
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("failed to read startup entry '%s' in %s: %w", newName, registryType, err)
+	}
+	if replaced {
+		if !options.overwrite {
+			return fmt.Errorf("startup entry '%s' already exists in %s", newName, registryType)
+		}
+		if metadata, err := GetEntryMetadataTyped(newName, registryType); err == nil && metadata.Pinned {
+			return fmt.Errorf("startup entry '%s' in %s: %w", newName, registryType, ErrEntryPinned)
+		}
+	}
+
+	metadata, err := GetEntryMetadataTyped(oldName, registryType)
+	hasMetadata := err == nil
+	if err != nil && !errors.Is(err, ErrNoMetadata) {
+		return err
+	}
+
+	approval, err := readApprovalBlob(oldName, registryType)
+	if err != nil {
+		return err
+	}
+
+	journal, err := openJournal()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	// Write the new name, then drop the old one, undoing the write if that fails
+	if valType == registry.EXPAND_SZ {
+		err = k.SetExpandStringValue(newName, command)
+	} else {
+		err = k.SetStringValue(newName, command)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set registry value: %w", err)
+	}
+	if err := k.DeleteValue(oldName); err != nil {
+		if !replaced {
+			_ = k.DeleteValue(newName)
+		}
+		return fmt.Errorf("failed to delete registry value: %w", err)
+	}
+
+	// The metadata follows the value, a replaced entry's metadata goes with it
+	if hasMetadata {
+		if err := SetEntryMetadata(newName, registryType, metadata); err != nil {
+			return fmt.Errorf("renamed '%s' to '%s' but failed to move its metadata: %w", oldName, newName, err)
+		}
+		if err := deleteEntryMetadata(oldName, registryType); err != nil {
+			return err
+		}
+	} else if replaced {
+		if err := deleteEntryMetadata(newName, registryType); err != nil {
+			return err
+		}
+	}
+
+	// So does the approval state, a blob left under the new name belongs to no entry
+	if approval != nil {
+		if err := writeApprovalBlob(newName, registryType, approval); err != nil {
+			return fmt.Errorf("renamed '%s' to '%s' but failed to move its approval state: %w", oldName, newName, err)
+		}
+		if err := deleteApprovalBlob(oldName, registryType); err != nil {
+			return err
+		}
+	} else if err := deleteApprovalBlob(newName, registryType); err != nil {
+		return err
+	}
+
+	if err := journal.record(JournalEntry{Operation: JournalRemove, Location: registryType, Name: oldName, Before: command}); err != nil {
+		return err
+	}
+	return journal.record(JournalEntry{Operation: JournalAdd, Location: registryType, Name: newName, Before: before, After: command})
+}
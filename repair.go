@@ -0,0 +1,67 @@
+package winstartupreg
+
+import (
+	"strings"
+)
+
+// Change describes a modification made, or proposed in dry-run mode, by one of
+// the repair operations
+type Change struct {
+	Location   StartupRegistryType // Registry location of the entry
+	Name       string              // Value name of the entry
+	OldCommand string              // Command before the repair
+	NewCommand string              // Command after the repair
+	Reason     string              // What was wrong with the old command
+	Applied    bool                // The new command was written to the registry
+}
+
+// RepairOption configures SelfRepair
+type RepairOption func(*repairOptions)
+
+type repairOptions struct {
+	dryRun      bool
+	installRoot string
+}
+
+// WithDryRun reports the changes a repair would make without writing them
+func WithDryRun() RepairOption {
+	return func(o *repairOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithInstallRoot sets the directory searched for executables that have moved.
+// It defaults to the directory of the running executable
+func WithInstallRoot(dir string) RepairOption {
+	return func(o *repairOptions) {
+		o.installRoot = dir
+	}
+}
+
+// repairMalformedCommand works out the repaired form of a command that has its
+// executable wrapped in doubled quotes or carries trailing whitespace or
+// semicolons. Arguments are kept as they are. It reports false when the command
+// is not malformed in one of these ways
+func repairMalformedCommand(command string) (string, string, bool) {
+	var reasons []string
+
+	repaired := trimCommandGarbage(command)
+	if repaired != command {
+		reasons = append(reasons, "trailing garbage")
+	}
+
+	if trimmed := strings.TrimLeft(repaired, " \t"); strings.HasPrefix(trimmed, `""`) {
+		if executable, rest, _ := splitCommand(trimmed); executable != "" {
+			repaired = `"` + executable + `"`
+			if rest = strings.TrimSpace(rest); rest != "" {
+				repaired += " " + rest
+			}
+			reasons = append(reasons, "doubled quotes")
+		}
+	}
+
+	if len(reasons) == 0 {
+		return "", "", false
+	}
+	return repaired, strings.Join(reasons, ", "), true
+}
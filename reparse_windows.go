@@ -0,0 +1,66 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// FindReparsePointEntries reports the entries whose executable path passes
+// through a reparse point, such as a symbolic link or a junction, in any of its
+// components. Whoever controls the reparse point can redirect the entry without
+// touching the registry. Entries are only reported, nothing is removed
+func FindReparsePointEntries() ([]StartupItem, error) {
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var found []StartupItem
+	var errs []error
+	for _, registryType := range registryTypes {
+		items, err := listStartupItems(registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, item := range items {
+			if traversesReparsePoint(CommandExecutable(item.Command)) {
+				found = append(found, item)
+			}
+		}
+	}
+
+	return found, errors.Join(errs...)
+}
+
+// traversesReparsePoint reports whether the path itself or any directory above it
+// carries the reparse point attribute. Components that do not exist are skipped
+func traversesReparsePoint(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	for {
+		if p, err := windows.UTF16PtrFromString(path); err == nil {
+			attrs, err := windows.GetFileAttributes(p)
+			if err == nil && attrs&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+				return true
+			}
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,92 @@
+package winstartupreg
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// ReportOption configures WriteReport
+type ReportOption func(*reportOptions)
+
+type reportOptions struct {
+	problemsOnly bool
+}
+
+// WithProblemsOnly limits the report to entries whose executable is missing or unsigned
+func WithProblemsOnly() ReportOption {
+	return func(o *reportOptions) {
+		o.problemsOnly = true
+	}
+}
+
+// WriteReport writes a column-aligned table of all startup entries with their
+// location, name, command, enabled state, whether the executable exists and
+// whether it is signed
+func WriteReport(w io.Writer, opts ...ReportOption) error {
+	options := reportOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	diagnostics, err := DiagnoseStartupEntries()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCATION\tNAME\tCOMMAND\tENABLED\tEXISTS\tSIGNED")
+
+	for _, d := range diagnostics {
+		if options.problemsOnly && !d.Problem() {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Location, d.Name, d.Command, yesNo(d.Enabled), yesNo(d.Exists), yesNo(d.Signed))
+	}
+
+	return tw.Flush()
+}
+
+// ExportCSV writes the same diagnostics as WriteReport as CSV for spreadsheets
+// and SIEM tools, with a header row and the columns location, name, command,
+// executable, enabled, exists and signed. Booleans are written as true or false
+func ExportCSV(w io.Writer) error {
+	diagnostics, err := DiagnoseStartupEntries()
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"location", "name", "command", "executable", "enabled", "exists", "signed"}); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, d := range diagnostics {
+		record := []string{
+			d.Location.String(),
+			d.Name,
+			d.Command,
+			d.Executable,
+			strconv.FormatBool(d.Enabled),
+			strconv.FormatBool(d.Exists),
+			strconv.FormatBool(d.Signed),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// yesNo renders a boolean report column
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,21 @@
+package winstartupreg
+
+// parseRunOnceName interprets the prefixes of a RunOnce value name. A leading !
+// defers deleting the value until the command has completed successfully, and a
+// leading * makes the command run even in Safe Mode. Both may be combined in
+// either order, the rest of the name is returned as cleanName
+func parseRunOnceName(stored string) (cleanName string, deleteOnlyOnSuccess, safeMode bool) {
+	for len(stored) > 0 {
+		switch {
+		case stored[0] == '!' && !deleteOnlyOnSuccess:
+			deleteOnlyOnSuccess = true
+		case stored[0] == '*' && !safeMode:
+			safeMode = true
+		default:
+			return stored, deleteOnlyOnSuccess, safeMode
+		}
+		stored = stored[1:]
+	}
+
+	return stored, deleteOnlyOnSuccess, safeMode
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// ResolveRunOnceEntry looks up a RunOnce entry of the current user, then of the
+// machine, and interprets the ! and * prefixes of its value name. name may be
+// given with or without the prefixes. The returned command is what Windows
+// launches, with environment variables expanded for REG_EXPAND_SZ values
+func ResolveRunOnceEntry(name string) (cleanName string, command string, deleteOnlyOnSuccess bool, safeMode bool, err error) {
+	wanted, _, _ := parseRunOnceName(name)
+
+	for _, registryType := range []StartupRegistryType{CurrentUserRunOnce, AllUsersRunOnce} {
+		items, err := listStartupItems(registryType)
+		if err != nil {
+			return "", "", false, false, err
+		}
+
+		for _, item := range items {
+			cleanName, deleteOnlyOnSuccess, safeMode := parseRunOnceName(item.Name)
+			if item.Name != name && cleanName != wanted {
+				continue
+			}
+
+			command := item.Command
+			if item.ValueType == registry.EXPAND_SZ {
+				if command, err = registry.ExpandString(command); err != nil {
+					return "", "", false, false, fmt.Errorf("failed to expand command: %w", err)
+				}
+			}
+			return cleanName, command, deleteOnlyOnSuccess, safeMode, nil
+		}
+	}
+
+	return "", "", false, false, fmt.Errorf("RunOnce entry '%s' not found: %w", name, ErrEntryNotFound)
+}
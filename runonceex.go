@@ -0,0 +1,81 @@
+package winstartupreg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RunOnceExItem is one value of a RunOnceEx section, in the order it runs
+type RunOnceExItem struct {
+	Key          string // RunOnceEx key under HKLM the section belongs to
+	Section      string // Name of the section subkey
+	SectionTitle string // Default value of the section subkey, shown in the progress dialog
+	Name         string // Value name
+	Value        string // Raw value data
+	DLL          string // DLL to load, empty for a plain command
+	Function     string // Function of DLL to call
+	Arguments    string // Command line passed to Function, or the command itself when DLL is empty
+}
+
+// parseRunOnceExValue splits a RunOnceEx value of the form DLL|Function|Arguments.
+// A value without separators names a DLL to load and register
+func parseRunOnceExValue(value string) (dll, function, arguments string) {
+	parts := strings.SplitN(value, "|", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// sortRunOnceExNames orders section or value names the way RunOnceEx processes
+// them: alphabetically, ignoring case
+func sortRunOnceExNames(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+}
+
+// orderRunOnceExKeys returns root and every RunOnceEx key it depends on, directly
+// or through other keys, in the order they run: each key once, after all the
+// keys named by its Depend subkey. depends returns those names for a key, in
+// the order they are processed. Keys are compared ignoring case, and a cycle
+// fails with ErrDependencyCycle naming the keys involved
+func orderRunOnceExKeys(root string, depends func(key string) ([]string, error)) ([]string, error) {
+	var order, open []string
+	done := make(map[string]bool)
+
+	var visit func(key string) error
+	visit = func(key string) error {
+		if done[strings.ToLower(key)] {
+			return nil
+		}
+		for i, pending := range open {
+			if strings.EqualFold(pending, key) {
+				chain := append(append([]string{}, open[i:]...), key)
+				return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(chain, " -> "))
+			}
+		}
+
+		open = append(open, key)
+		dependencies, err := depends(key)
+		if err != nil {
+			return err
+		}
+		for _, dependency := range dependencies {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		open = open[:len(open)-1]
+
+		done[strings.ToLower(key)] = true
+		order = append(order, key)
+		return nil
+	}
+
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,45 @@
+package winstartupreg_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+// dependsOn serves Depend lists from a map, the way readRunOnceExDepend reads them
+func dependsOn(graph map[string][]string) func(string) ([]string, error) {
+	return func(key string) ([]string, error) {
+		return graph[strings.ToLower(key)], nil
+	}
+}
+
+var _ = Describe("Ordering RunOnceEx Keys", func() {
+	It("Should run every key after the keys it depends on, each once", func() {
+		order, err := winstartupreg.OrderRunOnceExKeys("RunOnceEx", dependsOn(map[string][]string{
+			"runonceex": {"Drivers", "Apps"},
+			"apps":      {"Runtime", "drivers"},
+			"runtime":   {"Drivers"},
+		}))
+		Expect(err).To(BeNil())
+		Expect(order).To(Equal([]string{"Drivers", "Runtime", "Apps", "RunOnceEx"}))
+	})
+
+	It("Should return the key alone when it has no Depend subkey", func() {
+		order, err := winstartupreg.OrderRunOnceExKeys("RunOnceEx", dependsOn(nil))
+		Expect(err).To(BeNil())
+		Expect(order).To(Equal([]string{"RunOnceEx"}))
+	})
+
+	It("Should report a dependency cycle with the keys involved", func() {
+		_, err := winstartupreg.OrderRunOnceExKeys("RunOnceEx", dependsOn(map[string][]string{
+			"runonceex": {"Apps"},
+			"apps":      {"Runtime"},
+			"runtime":   {"APPS"},
+		}))
+		Expect(err).To(MatchError(winstartupreg.ErrDependencyCycle))
+		Expect(err).To(MatchError(ContainSubstring("Apps -> Runtime -> APPS")))
+	})
+})
@@ -0,0 +1,164 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// runOnceExPath is the RunOnceEx key of the machine
+const runOnceExPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnceEx`
+
+// runOnceExParent is the key Depend values without a path are resolved against
+const runOnceExParent = `SOFTWARE\Microsoft\Windows\CurrentVersion`
+
+// ListRunOnceEx reads the machine's RunOnceEx key in execution order. The keys
+// named by its Depend subkey run first, each after its own dependencies, and
+// then the key itself. Within a key, sections run in alphabetical order and the
+// values of each section in alphabetical order, both ignoring case. A Depend
+// value names a sibling key such as RunOnceEx2, or a path under HKLM, in the
+// order of the value names. Keys that depend on each other fail with
+// ErrDependencyCycle. A missing key yields no items
+func ListRunOnceEx() ([]RunOnceExItem, error) {
+	keys, err := orderRunOnceExKeys(runOnceExPath, readRunOnceExDepend)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []RunOnceExItem
+	for _, keyPath := range keys {
+		keyItems, err := readRunOnceExKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, keyItems...)
+	}
+
+	return items, nil
+}
+
+// readRunOnceExKey reads the sections of one RunOnceEx key in execution order
+func readRunOnceExKey(keyPath string) ([]RunOnceExItem, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer k.Close()
+
+	sections, err := k.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subkey names: %w", err)
+	}
+	sortRunOnceExNames(sections)
+
+	var items []RunOnceExItem
+	for _, section := range sections {
+		// Depend lists other keys, it is not a section
+		if strings.EqualFold(section, "Depend") {
+			continue
+		}
+
+		sectionItems, err := readRunOnceExSection(k, section)
+		if err != nil {
+			return nil, err
+		}
+		for i := range sectionItems {
+			sectionItems[i].Key = keyPath
+		}
+		items = append(items, sectionItems...)
+	}
+
+	return items, nil
+}
+
+// readRunOnceExDepend returns the keys named by the Depend subkey of a RunOnceEx
+// key, as paths under HKLM, in the order of their value names
+func readRunOnceExDepend(keyPath string) ([]string, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath+`\Depend`, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open Depend subkey of %s: %w", keyPath, err)
+	}
+	defer k.Close()
+
+	names, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value names: %w", err)
+	}
+	sortRunOnceExNames(names)
+
+	var keys []string
+	for _, name := range names {
+		value, _, err := k.GetStringValue(name)
+		if err != nil {
+			continue
+		}
+		if value = strings.Trim(strings.TrimSpace(value), `\`); value == "" {
+			continue
+		}
+
+		// A bare name is a sibling of RunOnceEx, anything else a path under HKLM
+		if !strings.Contains(value, `\`) {
+			value = runOnceExParent + `\` + value
+		}
+		for _, prefix := range []string{`HKEY_LOCAL_MACHINE\`, `HKLM\`} {
+			if len(value) > len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+				value = value[len(prefix):]
+			}
+		}
+		keys = append(keys, value)
+	}
+
+	return keys, nil
+}
+
+// readRunOnceExSection reads the values of one RunOnceEx section in execution order
+func readRunOnceExSection(parent registry.Key, section string) ([]RunOnceExItem, error) {
+	k, err := registry.OpenKey(parent, section, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open RunOnceEx section '%s': %w", section, err)
+	}
+	defer k.Close()
+
+	names, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value names: %w", err)
+	}
+	sortRunOnceExNames(names)
+
+	// The default value is the section title, not an item
+	title, _, _ := k.GetStringValue("")
+
+	var items []RunOnceExItem
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		value, _, err := k.GetStringValue(name)
+		if err != nil {
+			continue
+		}
+
+		dll, function, arguments := parseRunOnceExValue(value)
+		items = append(items, RunOnceExItem{
+			Section:      section,
+			SectionTitle: title,
+			Name:         name,
+			Value:        value,
+			DLL:          dll,
+			Function:     function,
+			Arguments:    arguments,
+		})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,87 @@
+package winstartupreg
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// ScheduledTask describes a Task Scheduler task that is triggered at user logon
+type ScheduledTask struct {
+	Name              string   // Task name
+	Path              string   // Full task path, e.g. \Vendor\Updater
+	Enabled           bool     // Whether the task itself is enabled
+	Principal         string   // User or group the task runs as
+	HighestPrivileges bool     // Whether the task runs with highest available privileges
+	Commands          []string // Executable actions, each as "command arguments"
+}
+
+// taskDefinitionXML is the subset of the Task Scheduler XML schema that is
+// needed to describe a logon task. Element names match any namespace
+type taskDefinitionXML struct {
+	Triggers struct {
+		Logon []struct {
+			Enabled *bool `xml:"Enabled"`
+		} `xml:"LogonTrigger"`
+	} `xml:"Triggers"`
+	Principals struct {
+		Principal []struct {
+			ID       string `xml:"id,attr"`
+			UserID   string `xml:"UserId"`
+			GroupID  string `xml:"GroupId"`
+			RunLevel string `xml:"RunLevel"`
+		} `xml:"Principal"`
+	} `xml:"Principals"`
+	Actions struct {
+		Context string `xml:"Context,attr"`
+		Exec    []struct {
+			Command   string `xml:"Command"`
+			Arguments string `xml:"Arguments"`
+		} `xml:"Exec"`
+	} `xml:"Actions"`
+}
+
+// parseLogonTaskXML fills in the principal and command details of a task from
+// its XML definition and reports whether it has an enabled logon trigger
+func parseLogonTaskXML(definition string, task *ScheduledTask) (bool, error) {
+	var def taskDefinitionXML
+
+	decoder := xml.NewDecoder(strings.NewReader(definition))
+	// The text is already decoded, ignore the UTF-16 declaration it carries
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&def); err != nil {
+		return false, err
+	}
+
+	// A logon trigger that has been switched off never fires
+	atLogon := false
+	for _, trigger := range def.Triggers.Logon {
+		if trigger.Enabled == nil || *trigger.Enabled {
+			atLogon = true
+			break
+		}
+	}
+
+	// Actions run in the context of the principal they reference
+	for i, principal := range def.Principals.Principal {
+		if i == 0 || principal.ID == def.Actions.Context {
+			task.Principal = principal.UserID
+			if task.Principal == "" {
+				task.Principal = principal.GroupID
+			}
+			task.HighestPrivileges = principal.RunLevel == "HighestAvailable"
+		}
+	}
+
+	for _, action := range def.Actions.Exec {
+		command := strings.TrimSpace(action.Command)
+		if args := strings.TrimSpace(action.Arguments); args != "" {
+			command += " " + args
+		}
+		task.Commands = append(task.Commands, command)
+	}
+
+	return atLogon, nil
+}
@@ -0,0 +1,102 @@
+package winstartupreg_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+// taskXML wraps triggers, principals and actions in a Task Scheduler definition
+// the way ITaskDefinition.XmlText returns it
+func taskXML(triggers, principals, actions string) string {
+	return `<?xml version="1.0" encoding="UTF-16"?>
+<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <Triggers>` + triggers + `</Triggers>
+  <Principals>` + principals + `</Principals>
+  ` + actions + `
+</Task>`
+}
+
+var _ = Describe("Parsing Logon Task Definitions", func() {
+	It("Should read the principal, run level and commands of an enabled logon task", func() {
+		definition := taskXML(
+			`<LogonTrigger><Enabled>true</Enabled></LogonTrigger>`,
+			`<Principal id="Author"><UserId>S-1-5-21-1-2-3-1001</UserId><RunLevel>HighestAvailable</RunLevel></Principal>`,
+			`<Actions Context="Author">
+    <Exec><Command>C:\Vendor\updater.exe</Command><Arguments> /logon </Arguments></Exec>
+    <Exec><Command>C:\Vendor\tray.exe</Command></Exec>
+  </Actions>`,
+		)
+
+		var task winstartupreg.ScheduledTask
+		atLogon, err := winstartupreg.ParseLogonTaskXML(definition, &task)
+		Expect(err).To(BeNil())
+		Expect(atLogon).To(BeTrue())
+		Expect(task.Principal).To(Equal("S-1-5-21-1-2-3-1001"))
+		Expect(task.HighestPrivileges).To(BeTrue())
+		Expect(task.Commands).To(Equal([]string{`C:\Vendor\updater.exe /logon`, `C:\Vendor\tray.exe`}))
+	})
+
+	It("Should not treat a disabled logon trigger as running at logon", func() {
+		definition := taskXML(
+			`<LogonTrigger><Enabled>false</Enabled></LogonTrigger>`,
+			`<Principal id="Author"><UserId>S-1-5-18</UserId></Principal>`,
+			`<Actions Context="Author"><Exec><Command>C:\Vendor\updater.exe</Command></Exec></Actions>`,
+		)
+
+		var task winstartupreg.ScheduledTask
+		atLogon, err := winstartupreg.ParseLogonTaskXML(definition, &task)
+		Expect(err).To(BeNil())
+		Expect(atLogon).To(BeFalse())
+	})
+
+	It("Should count a task with one disabled and one enabled logon trigger", func() {
+		definition := taskXML(
+			`<LogonTrigger><Enabled>false</Enabled></LogonTrigger><LogonTrigger/>`,
+			`<Principal id="Author"><UserId>S-1-5-18</UserId></Principal>`,
+			`<Actions Context="Author"><Exec><Command>C:\Vendor\updater.exe</Command></Exec></Actions>`,
+		)
+
+		var task winstartupreg.ScheduledTask
+		atLogon, err := winstartupreg.ParseLogonTaskXML(definition, &task)
+		Expect(err).To(BeNil())
+		Expect(atLogon).To(BeTrue())
+	})
+
+	It("Should take the principal the actions run in from several principals", func() {
+		definition := taskXML(
+			`<LogonTrigger/>`,
+			`<Principal id="Author"><UserId>S-1-5-21-1-2-3-1001</UserId><RunLevel>HighestAvailable</RunLevel></Principal>
+    <Principal id="Users"><GroupId>S-1-5-32-545</GroupId><RunLevel>LeastPrivilege</RunLevel></Principal>`,
+			`<Actions Context="Users"><Exec><Command>C:\Vendor\tray.exe</Command></Exec></Actions>`,
+		)
+
+		var task winstartupreg.ScheduledTask
+		atLogon, err := winstartupreg.ParseLogonTaskXML(definition, &task)
+		Expect(err).To(BeNil())
+		Expect(atLogon).To(BeTrue())
+		Expect(task.Principal).To(Equal("S-1-5-32-545"))
+		Expect(task.HighestPrivileges).To(BeFalse())
+	})
+
+	It("Should fall back to the group when the principal has no user", func() {
+		definition := taskXML(
+			`<LogonTrigger/>`,
+			`<Principal id="Users"><GroupId>S-1-5-32-545</GroupId><RunLevel>HighestAvailable</RunLevel></Principal>`,
+			`<Actions Context="Users"><Exec><Command>C:\Vendor\tray.exe</Command></Exec></Actions>`,
+		)
+
+		var task winstartupreg.ScheduledTask
+		_, err := winstartupreg.ParseLogonTaskXML(definition, &task)
+		Expect(err).To(BeNil())
+		Expect(task.Principal).To(Equal("S-1-5-32-545"))
+		Expect(task.HighestPrivileges).To(BeTrue())
+	})
+
+	It("Should report malformed XML", func() {
+		var task winstartupreg.ScheduledTask
+		_, err := winstartupreg.ParseLogonTaskXML("<Task>", &task)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,165 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	clsidTaskScheduler = windows.GUID{Data1: 0x0f87369f, Data2: 0xa4e5, Data3: 0x4cfc, Data4: [8]byte{0xbd, 0x3e, 0x73, 0xe6, 0x15, 0x45, 0x72, 0xdd}}
+	iidITaskService    = windows.GUID{Data1: 0x2faba4c7, Data2: 0x4da9, Data3: 0x4013, Data4: [8]byte{0x96, 0x97, 0x20, 0xcc, 0x3f, 0xd4, 0x0f, 0x85}}
+)
+
+// Vtable indices of the Task Scheduler 2.0 interfaces used here. All of them
+// derive from IDispatch, so their own methods start at index 7
+const (
+	taskServiceGetFolder = 7
+	taskServiceConnect   = 10
+
+	taskFolderGetFolders = 10
+	taskFolderGetTasks   = 14
+
+	collectionGetCount = 7
+	collectionGetItem  = 8
+
+	registeredTaskGetName    = 7
+	registeredTaskGetPath    = 8
+	registeredTaskGetEnabled = 10
+	registeredTaskGetXML     = 20
+
+	taskEnumHidden = 1
+)
+
+// eAccessDenied is ERROR_ACCESS_DENIED wrapped as an HRESULT
+const eAccessDenied = windows.Errno(0x80070005)
+
+// ListLogonScheduledTasks retrieves every scheduled task with a logon trigger,
+// including its enabled state, run-as principal, privilege level and commands.
+// Folders the caller is not allowed to read are skipped
+func ListLogonScheduledTasks() ([]ScheduledTask, error) {
+	var tasks []ScheduledTask
+
+	err := withCOM(func() error {
+		// Create and connect to the local task scheduler service
+		service, err := coCreateInstance(&clsidTaskScheduler, &iidITaskService)
+		if err != nil {
+			return fmt.Errorf("failed to create task scheduler service: %w", err)
+		}
+		defer service.Release()
+
+		var server, user, domain, password variant
+		args := append(variantArgs(&server), variantArgs(&user)...)
+		args = append(args, variantArgs(&domain)...)
+		args = append(args, variantArgs(&password)...)
+		if err := service.call(taskServiceConnect, args...); err != nil {
+			return fmt.Errorf("failed to connect to task scheduler: %w", err)
+		}
+
+		// Walk the folder tree from the root
+		rootPath, err := allocBSTR(`\`)
+		if err != nil {
+			return err
+		}
+		defer freeBSTR(rootPath)
+
+		var root *comObject
+		if err := service.call(taskServiceGetFolder, uintptr(unsafe.Pointer(rootPath)), uintptr(unsafe.Pointer(&root))); err != nil {
+			return fmt.Errorf("failed to open root task folder: %w", err)
+		}
+		defer root.Release()
+
+		tasks, err = collectLogonTasks(root, tasks)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// collectLogonTasks appends the logon tasks of folder and all of its subfolders to tasks
+func collectLogonTasks(folder *comObject, tasks []ScheduledTask) ([]ScheduledTask, error) {
+	var collection *comObject
+	if err := folder.call(taskFolderGetTasks, taskEnumHidden, uintptr(unsafe.Pointer(&collection))); err != nil {
+		return tasks, fmt.Errorf("failed to enumerate tasks: %w", err)
+	}
+
+	forEachItem(collection, func(registered *comObject) {
+		if task, ok := readLogonTask(registered); ok {
+			tasks = append(tasks, task)
+		}
+	})
+	collection.Release()
+
+	var folders *comObject
+	if err := folder.call(taskFolderGetFolders, 0, uintptr(unsafe.Pointer(&folders))); err != nil {
+		return tasks, fmt.Errorf("failed to enumerate task folders: %w", err)
+	}
+	defer folders.Release()
+
+	var walkErr error
+	forEachItem(folders, func(subfolder *comObject) {
+		var err error
+		tasks, err = collectLogonTasks(subfolder, tasks)
+		if err != nil && !errors.Is(err, eAccessDenied) && walkErr == nil {
+			walkErr = err
+		}
+	})
+
+	return tasks, walkErr
+}
+
+// forEachItem calls fn with every item of a one-based COM collection and
+// releases each item afterwards. Items that fail to load are skipped
+func forEachItem(collection *comObject, fn func(item *comObject)) {
+	var count int32
+	if err := collection.call(collectionGetCount, uintptr(unsafe.Pointer(&count))); err != nil {
+		return
+	}
+
+	for i := int32(1); i <= count; i++ {
+		index := newInt32Variant(i)
+		var item *comObject
+		args := append(variantArgs(&index), uintptr(unsafe.Pointer(&item)))
+		if err := collection.call(collectionGetItem, args...); err != nil {
+			continue
+		}
+		fn(item)
+		item.Release()
+	}
+}
+
+// readLogonTask reads a registered task and reports whether it is triggered at logon
+func readLogonTask(registered *comObject) (ScheduledTask, bool) {
+	var task ScheduledTask
+
+	var name, path, definition bstr
+	if err := registered.call(registeredTaskGetName, uintptr(unsafe.Pointer(&name))); err == nil {
+		task.Name = takeBSTR(name)
+	}
+	if err := registered.call(registeredTaskGetPath, uintptr(unsafe.Pointer(&path))); err == nil {
+		task.Path = takeBSTR(path)
+	}
+
+	var enabled int16 // VARIANT_BOOL, -1 is true
+	if err := registered.call(registeredTaskGetEnabled, uintptr(unsafe.Pointer(&enabled))); err == nil {
+		task.Enabled = enabled != 0
+	}
+
+	if err := registered.call(registeredTaskGetXML, uintptr(unsafe.Pointer(&definition))); err != nil {
+		return task, false
+	}
+
+	atLogon, err := parseLogonTaskXML(takeBSTR(definition), &task)
+	if err != nil {
+		return task, false
+	}
+
+	return task, atLogon
+}
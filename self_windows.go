@@ -0,0 +1,59 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RegisterSelf registers the running executable to start at logon in the given
+// location. Symlinks in its path are resolved. An empty name defaults to the base
+// name of the executable without its extension, so app.exe registers as "app"
+func RegisterSelf(name string, registryType StartupRegistryType) error {
+	self, err := currentExecutable()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = executableBaseName(self)
+	}
+
+	return AddStartupEntry(StartupEntry{Name: name, Command: self}, registryType)
+}
+
+// UnregisterSelf removes the entry of the running executable from every location
+// it was registered in, as SafeRemoveStartupEntry does. An empty name defaults to
+// the name RegisterSelf would have used
+func UnregisterSelf(name string) error {
+	if name == "" {
+		self, err := currentExecutable()
+		if err != nil {
+			return err
+		}
+		name = executableBaseName(self)
+	}
+
+	return SafeRemoveStartupEntry(name)
+}
+
+// currentExecutable returns the path of the running executable with symlinks resolved
+// where possible
+func currentExecutable() (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve current executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		self = resolved
+	}
+	return self, nil
+}
+
+// executableBaseName returns the file name of path without its extension
+func executableBaseName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
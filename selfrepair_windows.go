@@ -0,0 +1,216 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SelfRepair fixes the entries whose metadata names owner as their owner, and
+// only those. For each entry it corrects an executable that has moved, by
+// pointing it at the running executable when the names match or at a file of
+// the same name found under the install root, and it quotes executable paths
+// that contain spaces. Arguments are preserved. Every change is reported, and
+// nothing is written when WithDryRun is passed. A change is only applied if the
+// entry still holds the command it was planned from, otherwise it is reported
+// with Applied false
+func SelfRepair(owner string, opts ...RepairOption) ([]Change, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("owner cannot be empty")
+	}
+
+	options := repairOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if !options.dryRun {
+		if err := checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve current executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		self = resolved
+	}
+	if options.installRoot == "" {
+		options.installRoot = filepath.Dir(self)
+	}
+
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var changes []Change
+	var errs []error
+	for _, registryType := range registryTypes {
+		items, err := listStartupItems(registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, item := range items {
+			metadata, err := GetEntryMetadataTyped(item.Name, registryType)
+			if err != nil || metadata.Owner != owner {
+				continue
+			}
+
+			change, ok := repairOwnEntry(item, self, options.installRoot)
+			if !ok {
+				continue
+			}
+
+			if !options.dryRun {
+				// Swap only if nobody changed the entry since it was listed
+				swapped, err := CompareAndSwap(item.Name, change.OldCommand, change.NewCommand, registryType)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				change.Applied = swapped
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+// TouchSelfEntry points the entry called name at the running executable, for
+// updaters that install every version into a new folder. Each location holding
+// the entry is rewritten when its executable differs from os.Executable, keeping
+// the arguments. updated is false when the entry is absent or already correct
+func TouchSelfEntry(name string) (updated bool, err error) {
+	if err := checkWritable(); err != nil {
+		return false, err
+	}
+
+	// Validate input
+	if name == "" {
+		return false, fmt.Errorf("entry name cannot be empty")
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve current executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		self = resolved
+	}
+
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var errs []error
+	for _, registryType := range registryTypes {
+		present, err := EntryExists(name, registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !present {
+			continue
+		}
+
+		entry, err := captureEntry(name, registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if strings.EqualFold(filepath.Clean(CommandExecutable(entry.Command)), self) {
+			continue
+		}
+
+		// Keep a cmd /c start wrapper around the new target
+		prefix, target := "", entry.Command
+		if wrapper, inner, ok := unwrapCmdStart(trimCommandGarbage(entry.Command)); ok {
+			prefix, target = wrapper, inner
+		}
+
+		// Swap only if nobody changed the entry since it was read
+		_, rest, _ := splitCommand(target)
+		swapped, err := CompareAndSwap(name, entry.Command, prefix+composeCommand(self, rest), registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		updated = updated || swapped
+	}
+
+	return updated, errors.Join(errs...)
+}
+
+// repairOwnEntry works out the repaired command of an owned entry, if it needs one
+func repairOwnEntry(item StartupItem, self, installRoot string) (Change, bool) {
+	// Find the executable the way Windows does, an unquoted path may hold spaces
+	executable, rest, quoted := splitProbedCommand(item.Command, os.LookupEnv)
+	var reasons []string
+
+	// Relocate an executable that is no longer where the entry points
+	if !isRegularFile(CommandExecutable(composeCommand(executable, ""))) {
+		if moved := findMovedExecutable(executable, self, installRoot); moved != "" {
+			executable = moved
+			quoted = false
+			reasons = append(reasons, "executable moved")
+		}
+	}
+
+	// Quote paths containing spaces so Windows does not split them
+	if !quoted && strings.ContainsAny(executable, " \t") {
+		reasons = append(reasons, "unquoted path with spaces")
+	}
+
+	newCommand := composeCommand(executable, rest)
+	if len(reasons) == 0 || newCommand == item.Command {
+		return Change{}, false
+	}
+
+	return Change{
+		Location:   item.Location,
+		Name:       item.Name,
+		OldCommand: item.Command,
+		NewCommand: newCommand,
+		Reason:     strings.Join(reasons, ", "),
+	}, true
+}
+
+// findMovedExecutable looks for the new location of a missing executable: the
+// running executable when the file names match, otherwise the first file of the
+// same name under installRoot. It returns "" when nothing is found
+func findMovedExecutable(executable, self, installRoot string) string {
+	base := filepath.Base(executable)
+	if strings.EqualFold(base, filepath.Base(self)) {
+		return self
+	}
+
+	var found string
+	_ = filepath.WalkDir(installRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && strings.EqualFold(d.Name(), base) {
+			found = path
+			return fs.SkipAll
+		}
+		return nil
+	})
+
+	return found
+}
@@ -0,0 +1,120 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	clsidShellLink  = windows.GUID{Data1: 0x00021401, Data2: 0x0000, Data3: 0x0000, Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+	iidIShellLinkW  = windows.GUID{Data1: 0x000214f9, Data2: 0x0000, Data3: 0x0000, Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+	iidIPersistFile = windows.GUID{Data1: 0x0000010b, Data2: 0x0000, Data3: 0x0000, Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+)
+
+// Vtable indices of the shell link interfaces used here, counting the three
+// IUnknown methods and, for IPersistFile, IPersist::GetClassID
+const (
+	shellLinkGetPath             = 3
+	shellLinkSetWorkingDirectory = 9
+	shellLinkGetArguments        = 10
+	shellLinkSetArguments        = 11
+	shellLinkSetPath             = 20
+
+	persistFileLoad = 5
+	persistFileSave = 6
+
+	stgmRead = 0
+)
+
+// resolveShortcut returns the target path of a .lnk file, with environment
+// variables expanded
+func resolveShortcut(path string) (string, error) {
+	target, _, err := readShortcut(path)
+	return target, err
+}
+
+// readShortcut returns the target path of a .lnk file, with environment variables
+// expanded, and its raw argument text
+func readShortcut(path string) (target, args string, err error) {
+	err = withShellLink(func(link, file *comObject) error {
+		name, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			return err
+		}
+		if err := file.call(persistFileLoad, uintptr(unsafe.Pointer(name)), stgmRead); err != nil {
+			return fmt.Errorf("failed to load shortcut %s: %w", path, err)
+		}
+
+		buf := make([]uint16, windows.MAX_LONG_PATH)
+		if err := link.call(shellLinkGetPath, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0); err != nil {
+			return fmt.Errorf("failed to read shortcut target of %s: %w", path, err)
+		}
+		target = windows.UTF16ToString(buf)
+
+		buf = make([]uint16, windows.MAX_LONG_PATH)
+		if err := link.call(shellLinkGetArguments, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))); err != nil {
+			return fmt.Errorf("failed to read shortcut arguments of %s: %w", path, err)
+		}
+		args = windows.UTF16ToString(buf)
+		return nil
+	})
+
+	return target, args, err
+}
+
+// writeShortcut saves a .lnk file at path that starts target with the raw argument
+// text args in the working directory dir
+func writeShortcut(path, target, args, dir string) error {
+	return withShellLink(func(link, file *comObject) error {
+		for _, field := range []struct {
+			index int
+			value string
+			what  string
+		}{
+			{shellLinkSetPath, target, "target"},
+			{shellLinkSetArguments, args, "arguments"},
+			{shellLinkSetWorkingDirectory, dir, "working directory"},
+		} {
+			value, err := windows.UTF16PtrFromString(field.value)
+			if err != nil {
+				return err
+			}
+			if err := link.call(field.index, uintptr(unsafe.Pointer(value))); err != nil {
+				return fmt.Errorf("failed to set shortcut %s: %w", field.what, err)
+			}
+		}
+
+		name, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			return err
+		}
+		if err := file.call(persistFileSave, uintptr(unsafe.Pointer(name)), 1); err != nil {
+			return fmt.Errorf("failed to save shortcut %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// withShellLink creates a ShellLink object and runs fn with its IShellLinkW and
+// IPersistFile interfaces on a COM thread
+func withShellLink(fn func(link, file *comObject) error) error {
+	return withCOM(func() error {
+		link, err := coCreateInstance(&clsidShellLink, &iidIShellLinkW)
+		if err != nil {
+			return fmt.Errorf("failed to create shell link: %w", err)
+		}
+		defer link.Release()
+
+		file, err := link.QueryInterface(&iidIPersistFile)
+		if err != nil {
+			return fmt.Errorf("failed to query persist file: %w", err)
+		}
+		defer file.Release()
+
+		return fn(link, file)
+	})
+}
@@ -0,0 +1,236 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modcrypt32  = windows.NewLazySystemDLL("crypt32.dll")
+	modwintrust = windows.NewLazySystemDLL("wintrust.dll")
+
+	procCryptMsgGetParam = modcrypt32.NewProc("CryptMsgGetParam")
+	procCryptMsgClose    = modcrypt32.NewProc("CryptMsgClose")
+
+	procCryptCATAdminAcquireContext2         = modwintrust.NewProc("CryptCATAdminAcquireContext2")
+	procCryptCATAdminReleaseContext          = modwintrust.NewProc("CryptCATAdminReleaseContext")
+	procCryptCATAdminCalcHashFromFileHandle2 = modwintrust.NewProc("CryptCATAdminCalcHashFromFileHandle2")
+	procCryptCATAdminEnumCatalogFromHash     = modwintrust.NewProc("CryptCATAdminEnumCatalogFromHash")
+	procCryptCATAdminReleaseCatalogContext   = modwintrust.NewProc("CryptCATAdminReleaseCatalogContext")
+	procCryptCATCatalogInfoFromContext       = modwintrust.NewProc("CryptCATCatalogInfoFromContext")
+)
+
+const cmsgSignerInfoParam = 6
+
+// catalogHashAlgorithms are tried in order when looking a file up in the system
+// catalogs. Current catalogs use SHA256, older ones SHA1
+var catalogHashAlgorithms = []string{"SHA256", "SHA1"}
+
+// wintrustCatalogInfo is WINTRUST_CATALOG_INFO
+type wintrustCatalogInfo struct {
+	Size               uint32
+	CatalogVersion     uint32
+	CatalogFilePath    *uint16
+	MemberTag          *uint16
+	MemberFilePath     *uint16
+	MemberFile         windows.Handle
+	CalculatedFileHash *byte
+	CalculatedHashSize uint32
+	CatalogContext     uintptr
+	CatAdmin           windows.Handle
+}
+
+// catalogInfo is CATALOG_INFO
+type catalogInfo struct {
+	Size        uint32
+	CatalogFile [windows.MAX_PATH]uint16
+}
+
+// cmsgSignerInfo is the leading part of CMSG_SIGNER_INFO, which is all that
+// is needed to find the signing certificate in the message store
+type cmsgSignerInfo struct {
+	Version      uint32
+	Issuer       windows.CertNameBlob
+	SerialNumber windows.CryptIntegerBlob
+}
+
+// verifySignature checks the Authenticode signature of a file with
+// WinVerifyTrust. A file without an embedded signature, like many inbox Windows
+// binaries, is looked up in the system catalogs instead. Revocation is not
+// checked so the result does not depend on network access
+func verifySignature(path string) error {
+	path16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	embeddedErr := winVerifyTrust(windows.WTD_CHOICE_FILE, unsafe.Pointer(&windows.WinTrustFileInfo{
+		Size:     uint32(unsafe.Sizeof(windows.WinTrustFileInfo{})),
+		FilePath: path16,
+	}))
+	if !errors.Is(embeddedErr, windows.Errno(windows.TRUST_E_NOSIGNATURE)) &&
+		!errors.Is(embeddedErr, windows.Errno(windows.TRUST_E_SUBJECT_FORM_UNKNOWN)) &&
+		!errors.Is(embeddedErr, windows.Errno(windows.TRUST_E_PROVIDER_UNKNOWN)) {
+		return embeddedErr
+	}
+
+	// Report the missing embedded signature unless a catalog vouches for the file
+	for _, algorithm := range catalogHashAlgorithms {
+		if err := verifyCatalogSignature(path, path16, algorithm); err == nil {
+			return nil
+		}
+	}
+
+	return embeddedErr
+}
+
+// winVerifyTrust runs the generic Authenticode policy on a file or catalog
+// member and releases the verification state again
+func winVerifyTrust(choice uint32, info unsafe.Pointer) error {
+	data := &windows.WinTrustData{
+		Size:                            uint32(unsafe.Sizeof(windows.WinTrustData{})),
+		UIChoice:                        windows.WTD_UI_NONE,
+		RevocationChecks:                windows.WTD_REVOKE_NONE,
+		UnionChoice:                     choice,
+		StateAction:                     windows.WTD_STATEACTION_VERIFY,
+		FileOrCatalogOrBlobOrSgnrOrCert: info,
+	}
+
+	verifyErr := windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+	data.StateAction = windows.WTD_STATEACTION_CLOSE
+	_ = windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+
+	return verifyErr
+}
+
+// verifyCatalogSignature hashes a file with algorithm, finds a system catalog
+// listing that hash and verifies the file as a member of that catalog
+func verifyCatalogSignature(path string, path16 *uint16, algorithm string) error {
+	// CryptCATAdminAcquireContext2 needs Windows 8
+	if err := procCryptCATAdminAcquireContext2.Find(); err != nil {
+		return err
+	}
+
+	algorithm16, err := windows.UTF16PtrFromString(algorithm)
+	if err != nil {
+		return err
+	}
+
+	var admin windows.Handle
+	if r, _, err := procCryptCATAdminAcquireContext2.Call(uintptr(unsafe.Pointer(&admin)), 0, uintptr(unsafe.Pointer(algorithm16)), 0, 0); r == 0 {
+		return fmt.Errorf("failed to acquire catalog context: %w", err)
+	}
+	defer procCryptCATAdminReleaseContext.Call(uintptr(admin), 0)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Ask for the hash size first, then compute the hash
+	var size uint32
+	procCryptCATAdminCalcHashFromFileHandle2.Call(uintptr(admin), file.Fd(), uintptr(unsafe.Pointer(&size)), 0, 0)
+	if size == 0 {
+		return fmt.Errorf("failed to hash %s for a catalog lookup", path)
+	}
+	hash := make([]byte, size)
+	if r, _, err := procCryptCATAdminCalcHashFromFileHandle2.Call(uintptr(admin), file.Fd(), uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&hash[0])), 0); r == 0 {
+		return fmt.Errorf("failed to hash %s for a catalog lookup: %w", path, err)
+	}
+
+	catalog, _, err := procCryptCATAdminEnumCatalogFromHash.Call(uintptr(admin), uintptr(unsafe.Pointer(&hash[0])), uintptr(size), 0, 0)
+	if catalog == 0 {
+		return fmt.Errorf("no catalog lists %s: %w", path, err)
+	}
+	defer procCryptCATAdminReleaseCatalogContext.Call(uintptr(admin), catalog, 0)
+
+	info := catalogInfo{Size: uint32(unsafe.Sizeof(catalogInfo{}))}
+	if r, _, err := procCryptCATCatalogInfoFromContext.Call(catalog, uintptr(unsafe.Pointer(&info)), 0); r == 0 {
+		return fmt.Errorf("failed to read catalog info: %w", err)
+	}
+
+	// Catalog members are tagged with the hex encoded hash
+	tag, err := windows.UTF16PtrFromString(strings.ToUpper(fmt.Sprintf("%x", hash)))
+	if err != nil {
+		return err
+	}
+
+	return winVerifyTrust(windows.WTD_CHOICE_CATALOG, unsafe.Pointer(&wintrustCatalogInfo{
+		Size:               uint32(unsafe.Sizeof(wintrustCatalogInfo{})),
+		CatalogFilePath:    &info.CatalogFile[0],
+		MemberTag:          tag,
+		MemberFilePath:     path16,
+		MemberFile:         windows.Handle(file.Fd()),
+		CalculatedFileHash: &hash[0],
+		CalculatedHashSize: size,
+		CatAdmin:           admin,
+	}))
+}
+
+// signerName returns the display name of the certificate that signed a file
+// with an embedded Authenticode signature. It does not verify the signature
+func signerName(path string) (string, error) {
+	path16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+
+	var encoding, contentType, formatType uint32
+	var store, msg windows.Handle
+	err = windows.CryptQueryObject(
+		windows.CERT_QUERY_OBJECT_FILE,
+		unsafe.Pointer(path16),
+		windows.CERT_QUERY_CONTENT_FLAG_PKCS7_SIGNED_EMBED,
+		windows.CERT_QUERY_FORMAT_FLAG_BINARY,
+		0,
+		&encoding, &contentType, &formatType,
+		&store, &msg, nil,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signature: %w", err)
+	}
+	defer windows.CertCloseStore(store, 0)
+	defer procCryptMsgClose.Call(uintptr(msg))
+
+	// Read the signer info to learn which certificate in the store signed the file
+	var size uint32
+	if r, _, err := procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerInfoParam, 0, 0, uintptr(unsafe.Pointer(&size))); r == 0 {
+		return "", fmt.Errorf("failed to read signer info: %w", err)
+	}
+	buf := make([]byte, size)
+	if r, _, err := procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerInfoParam, 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size))); r == 0 {
+		return "", fmt.Errorf("failed to read signer info: %w", err)
+	}
+	signer := (*cmsgSignerInfo)(unsafe.Pointer(&buf[0]))
+
+	certInfo := windows.CertInfo{
+		Issuer:       signer.Issuer,
+		SerialNumber: signer.SerialNumber,
+	}
+	cert, err := windows.CertFindCertificateInStore(
+		store,
+		windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING,
+		0,
+		windows.CERT_FIND_SUBJECT_CERT,
+		unsafe.Pointer(&certInfo),
+		nil,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to find signing certificate: %w", err)
+	}
+	defer windows.CertFreeCertificateContext(cert)
+
+	n := windows.CertGetNameString(cert, windows.CERT_NAME_SIMPLE_DISPLAY_TYPE, 0, nil, nil, 0)
+	name := make([]uint16, n)
+	windows.CertGetNameString(cert, windows.CERT_NAME_SIMPLE_DISPLAY_TYPE, 0, nil, &name[0], n)
+
+	return windows.UTF16ToString(name), nil
+}
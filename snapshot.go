@@ -0,0 +1,234 @@
+package winstartupreg
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is a point-in-time copy of the startup entries of every known location
+type Snapshot struct {
+	TakenAt time.Time                                 `json:"takenAt"`
+	Entries map[StartupRegistryType]map[string]string `json:"entries"`
+
+	// DriveLettersNormalized marks snapshots whose commands had their drive
+	// letters replaced by placeholders such as <SYSTEMDRIVE>. Such commands are
+	// meant for comparison only and must not be written back to the registry
+	DriveLettersNormalized bool `json:"driveLettersNormalized,omitempty"`
+}
+
+// SnapshotOption configures TakeSnapshot
+type SnapshotOption func(*snapshotOptions)
+
+type snapshotOptions struct {
+	normalizeDrives bool
+}
+
+// WithDriveLetterNormalization replaces the drive letter of paths that live under
+// a known environment root with a placeholder, so the same install on C: and D:
+// compares equal across machines. Paths on the system drive become
+// <SYSTEMDRIVE>\..., paths under another root such as %ProgramFiles% on a
+// different drive become <PROGRAMFILESDRIVE>\..., other paths are left untouched
+func WithDriveLetterNormalization() SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.normalizeDrives = true
+	}
+}
+
+// TakeSnapshot copies the entries of every known location
+func TakeSnapshot(opts ...SnapshotOption) (Snapshot, error) {
+	options := snapshotOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	entries, err := ListAllStartupEntries()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot := Snapshot{
+		TakenAt: time.Now().UTC(),
+		Entries: entries,
+	}
+
+	if options.normalizeDrives {
+		for _, location := range snapshot.Entries {
+			for name, command := range location {
+				location[name] = normalizeDriveLetters(command, os.LookupEnv)
+			}
+		}
+		snapshot.DriveLettersNormalized = true
+	}
+
+	return snapshot, nil
+}
+
+// driveRoots are the environment variables whose directories identify an
+// install root when normalizing drive letters, most specific first
+var driveRoots = []string{
+	"LOCALAPPDATA",
+	"APPDATA",
+	"USERPROFILE",
+	"ProgramFiles(x86)",
+	"ProgramW6432",
+	"ProgramFiles",
+	"ProgramData",
+	"SystemRoot",
+}
+
+// normalizeDriveLetters replaces the drive letter of every absolute path in a
+// command as described by WithDriveLetterNormalization
+func normalizeDriveLetters(command string, lookup func(string) (string, bool)) string {
+	systemDrive, _ := lookup("SystemDrive")
+
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if !isDriveAt(command, i) {
+			b.WriteByte(command[i])
+			continue
+		}
+
+		drive := command[i : i+2]
+		placeholder := ""
+		if systemDrive != "" && strings.EqualFold(drive, systemDrive) {
+			placeholder = "<SYSTEMDRIVE>"
+		} else {
+			for _, root := range driveRoots {
+				dir, ok := lookup(root)
+				if ok && len(dir) > 2 && isDriveAt(dir, 0) && hasFoldPrefix(command[i:], dir) {
+					placeholder = "<" + strings.ToUpper(strings.TrimSuffix(root, "(x86)")) + "DRIVE>"
+					break
+				}
+			}
+		}
+
+		if placeholder == "" {
+			b.WriteByte(command[i])
+			continue
+		}
+		b.WriteString(placeholder)
+		i++ // skip the colon as well
+	}
+
+	return b.String()
+}
+
+// isDriveAt reports whether s holds a drive-qualified absolute path such as C:\ at index i
+func isDriveAt(s string, i int) bool {
+	if i+2 >= len(s) || s[i+1] != ':' || s[i+2] != '\\' {
+		return false
+	}
+	c := s[i] | 0x20
+	if c < 'a' || c > 'z' {
+		return false
+	}
+	// The letter must start a token, not end a word like "abc:"
+	return i == 0 || strings.IndexByte(" \t\"'=,;", s[i-1]) >= 0
+}
+
+// hasFoldPrefix reports whether s starts with the directory dir, ignoring case
+func hasFoldPrefix(s, dir string) bool {
+	dir = strings.TrimSuffix(dir, `\`)
+	if len(s) < len(dir) || !strings.EqualFold(s[:len(dir)], dir) {
+		return false
+	}
+	return len(s) == len(dir) || strings.IndexByte("\\\" ", s[len(dir)]) >= 0
+}
+
+// EntryDiff describes a single entry that differs between two states
+type EntryDiff struct {
+	Location   StartupRegistryType
+	Name       string
+	OldCommand string // Empty for added entries
+	NewCommand string // Empty for removed entries
+
+	// OldValueType and NewValueType hold the registry value types where they are
+	// known, so a switch between REG_SZ and REG_EXPAND_SZ shows up as a change.
+	// Snapshots do not record value types and leave both zero
+	OldValueType uint32
+	NewValueType uint32
+}
+
+// SnapshotDiff lists the entries that were added, removed or changed between
+// two states, each sorted by location and name
+type SnapshotDiff struct {
+	Added   []EntryDiff
+	Removed []EntryDiff
+	Changed []EntryDiff
+}
+
+// Empty reports whether the two states were identical
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSnapshots compares two snapshots and reports how to get from old to new
+func DiffSnapshots(old, new Snapshot) SnapshotDiff {
+	return diffEntries(snapshotValues(old), snapshotValues(new))
+}
+
+// entryValue is the stored data of an entry together with its value type
+type entryValue struct {
+	Command   string
+	ValueType uint32
+}
+
+// snapshotValues converts the entries of a snapshot to untyped entry values
+func snapshotValues(snapshot Snapshot) map[StartupRegistryType]map[string]entryValue {
+	values := make(map[StartupRegistryType]map[string]entryValue)
+	for location, entries := range snapshot.Entries {
+		values[location] = make(map[string]entryValue)
+		for name, command := range entries {
+			values[location][name] = entryValue{Command: command}
+		}
+	}
+	return values
+}
+
+// diffEntries compares two sets of entry values location by location
+func diffEntries(old, new map[StartupRegistryType]map[string]entryValue) SnapshotDiff {
+	var diff SnapshotDiff
+
+	for location, entries := range old {
+		for name, before := range entries {
+			after, ok := new[location][name]
+			switch {
+			case !ok:
+				diff.Removed = append(diff.Removed, EntryDiff{
+					Location: location, Name: name,
+					OldCommand: before.Command, OldValueType: before.ValueType,
+				})
+			case after != before:
+				diff.Changed = append(diff.Changed, EntryDiff{
+					Location: location, Name: name,
+					OldCommand: before.Command, OldValueType: before.ValueType,
+					NewCommand: after.Command, NewValueType: after.ValueType,
+				})
+			}
+		}
+	}
+
+	for location, entries := range new {
+		for name, after := range entries {
+			if _, ok := old[location][name]; !ok {
+				diff.Added = append(diff.Added, EntryDiff{
+					Location: location, Name: name,
+					NewCommand: after.Command, NewValueType: after.ValueType,
+				})
+			}
+		}
+	}
+
+	for _, changes := range [][]EntryDiff{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(changes, func(i, j int) bool {
+			if changes[i].Location != changes[j].Location {
+				return changes[i].Location < changes[j].Location
+			}
+			return changes[i].Name < changes[j].Name
+		})
+	}
+
+	return diff
+}
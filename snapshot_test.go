@@ -0,0 +1,51 @@
+package winstartupreg_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+var _ = Describe("Snapshot Diff", func() {
+	It("Should report added, removed and changed entries sorted by location and name", func() {
+		old := winstartupreg.Snapshot{Entries: map[winstartupreg.StartupRegistryType]map[string]string{
+			winstartupreg.CurrentUserRun: {"Kept": `C:\kept.exe`, "Moved": `C:\old.exe`, "Gone": `C:\gone.exe`},
+		}}
+		new := winstartupreg.Snapshot{Entries: map[winstartupreg.StartupRegistryType]map[string]string{
+			winstartupreg.CurrentUserRun: {"Kept": `C:\kept.exe`, "Moved": `D:\new.exe`},
+			winstartupreg.AllUsersRun:    {"Fresh": `C:\fresh.exe`},
+		}}
+
+		diff := winstartupreg.DiffSnapshots(old, new)
+		Expect(diff.Empty()).To(BeFalse())
+		Expect(diff.Added).To(Equal([]winstartupreg.EntryDiff{
+			{Location: winstartupreg.AllUsersRun, Name: "Fresh", NewCommand: `C:\fresh.exe`},
+		}))
+		Expect(diff.Removed).To(Equal([]winstartupreg.EntryDiff{
+			{Location: winstartupreg.CurrentUserRun, Name: "Gone", OldCommand: `C:\gone.exe`},
+		}))
+		Expect(diff.Changed).To(Equal([]winstartupreg.EntryDiff{
+			{Location: winstartupreg.CurrentUserRun, Name: "Moved", OldCommand: `C:\old.exe`, NewCommand: `D:\new.exe`},
+		}))
+
+		Expect(winstartupreg.DiffSnapshots(new, new).Empty()).To(BeTrue())
+	})
+
+	It("Should fingerprint snapshots by their entries only", func() {
+		a := winstartupreg.Snapshot{Entries: map[winstartupreg.StartupRegistryType]map[string]string{
+			winstartupreg.CurrentUserRun: {"App": `C:\app.exe`, "Tool": `C:\tool.exe`},
+		}}
+		b := winstartupreg.Snapshot{TakenAt: time.Now(), Entries: map[winstartupreg.StartupRegistryType]map[string]string{
+			winstartupreg.CurrentUserRun: {"Tool": `C:\tool.exe`, "App": `C:\app.exe`},
+		}}
+		moved := winstartupreg.Snapshot{Entries: map[winstartupreg.StartupRegistryType]map[string]string{
+			winstartupreg.AllUsersRun: {"App": `C:\app.exe`, "Tool": `C:\tool.exe`},
+		}}
+
+		Expect(a.Fingerprint()).To(Equal(b.Fingerprint()))
+		Expect(a.Fingerprint()).ToNot(Equal(moved.Fingerprint()))
+	})
+})
@@ -0,0 +1,116 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// AddStartupShortcut creates, or replaces, a shortcut named after the entry in the
+// current user's Startup folder (shell:startup). The shortcut starts the entry's
+// executable with its Args, in the directory that holds the executable
+func AddStartupShortcut(entry StartupEntry) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	// Validate input
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	// Normalize and validate command path
+	fullPath, err := resolveCommand(entry.Command)
+	if err != nil {
+		return err
+	}
+
+	path, err := startupShortcutPath(entry.Name)
+	if err != nil {
+		return err
+	}
+
+	return writeShortcut(path, fullPath, joinArgs(entry.Args), filepath.Dir(fullPath))
+}
+
+// RemoveStartupShortcut deletes a shortcut created by AddStartupShortcut from the
+// current user's Startup folder. A missing shortcut gives ErrEntryNotFound
+func RemoveStartupShortcut(name string) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	path, err := startupShortcutPath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("startup shortcut '%s' not found: %w", name, ErrEntryNotFound)
+		}
+		return fmt.Errorf("failed to remove startup shortcut: %w", err)
+	}
+
+	return nil
+}
+
+// ListStartupShortcuts resolves the shortcuts in the current user's Startup
+// folder, keyed by file name without the .lnk extension. Each command is the
+// shortcut target, quoted when it contains spaces, followed by its arguments.
+// Other files in the folder are not included, see ListEverything for those
+func ListStartupShortcuts() (map[string]string, error) {
+	dir, err := windows.KnownFolderPath(windows.FOLDERID_Startup, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate startup folder: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read startup folder: %w", err)
+	}
+
+	shortcuts := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() || !strings.EqualFold(filepath.Ext(file.Name()), ".lnk") {
+			continue
+		}
+
+		// A shortcut that cannot be read is skipped like an unreadable registry value
+		target, args, err := readShortcut(filepath.Join(dir, file.Name()))
+		if err != nil || target == "" {
+			continue
+		}
+		shortcuts[strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))] = composeCommand(target, args)
+	}
+
+	return shortcuts, nil
+}
+
+// startupShortcutPath returns the path of the shortcut for name in the current
+// user's Startup folder. Names that are not plain file names are rejected, so no
+// path outside the folder can be built from them
+func startupShortcutPath(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("entry name cannot be empty")
+	}
+	if strings.ContainsAny(name, `\/:*?"<>|`) {
+		return "", fmt.Errorf("entry name '%s' cannot be used as a file name", name)
+	}
+
+	dir, err := windows.KnownFolderPath(windows.FOLDERID_Startup, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate startup folder: %w", err)
+	}
+
+	return filepath.Join(dir, name+".lnk"), nil
+}
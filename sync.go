@@ -0,0 +1,71 @@
+package winstartupreg
+
+import (
+	"time"
+)
+
+// SyncAction is what a sync step does to an entry
+type SyncAction int
+
+const (
+	// SyncAdd creates an entry that is desired but missing
+	SyncAdd SyncAction = iota
+	// SyncUpdate rewrites an entry whose command differs from the desired one
+	SyncUpdate
+	// SyncRemove deletes an owned entry that is no longer desired
+	SyncRemove
+)
+
+// String returns the name of the action
+func (a SyncAction) String() string {
+	switch a {
+	case SyncAdd:
+		return "add"
+	case SyncUpdate:
+		return "update"
+	case SyncRemove:
+		return "remove"
+	default:
+		return "unknown"
+	}
+}
+
+// SyncStep is a single change of a SyncPlan
+type SyncStep struct {
+	Action     SyncAction
+	Name       string
+	OldCommand string // Empty for SyncAdd
+	NewCommand string // Empty for SyncRemove
+
+	valueType uint32 // Value type to keep when updating
+}
+
+// SyncPlan is the set of changes that brings the entries of an owner in one
+// location in line with the desired ones, as computed by PlanSync
+type SyncPlan struct {
+	Owner    string
+	Location StartupRegistryType
+	Steps    []SyncStep
+
+	// State of the location when the plan was made, for WithStaleCheck
+	lastWrite   time.Time
+	fingerprint string
+}
+
+// ApplyOption configures ApplyPlan
+type ApplyOption func(*applyOptions)
+
+type applyOptions struct {
+	staleCheck bool
+}
+
+// WithStaleCheck makes ApplyPlan verify that the location is still in the state
+// the plan was computed from, and fail with ErrStalePlan without changing
+// anything if another process modified it in the meantime. The key's last-write
+// time is compared first, and only when it moved are the values themselves
+// compared, so a touch that left every value intact is not treated as a change
+func WithStaleCheck() ApplyOption {
+	return func(o *applyOptions) {
+		o.staleCheck = true
+	}
+}
@@ -0,0 +1,165 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// PlanSync works out the changes that make the entries owned by owner in a
+// location match desired: missing entries are added, entries with a different
+// command are updated and owned entries that are not desired are removed.
+// Ownership is read from the entry metadata, entries of other owners are never
+// touched. The executable of every desired command must exist. Nothing is
+// written, pass the plan to ApplyPlan to carry it out
+func PlanSync(owner string, desired []StartupEntry, registryType StartupRegistryType) (SyncPlan, error) {
+	if owner == "" {
+		return SyncPlan{}, fmt.Errorf("owner cannot be empty")
+	}
+
+	wanted := make(map[string]string)
+	for _, entry := range desired {
+		if entry.Name == "" {
+			return SyncPlan{}, fmt.Errorf("entry name cannot be empty")
+		}
+		if executable := CommandExecutable(entry.Command); !isRegularFile(executable) {
+			return SyncPlan{}, fmt.Errorf("executable does not exist: %s", executable)
+		}
+		wanted[entry.Name] = entryCommandLine(entry.Command, entry.Command, entry.Args)
+	}
+
+	lastWrite, fingerprint, items, err := readLocationState(registryType)
+	if err != nil {
+		return SyncPlan{}, err
+	}
+
+	plan := SyncPlan{
+		Owner:       owner,
+		Location:    registryType,
+		lastWrite:   lastWrite,
+		fingerprint: fingerprint,
+	}
+
+	current := make(map[string]StartupItem)
+	for _, item := range items {
+		current[item.Name] = item
+
+		command, ok := wanted[item.Name]
+		switch {
+		case ok && command != item.Command:
+			plan.Steps = append(plan.Steps, SyncStep{
+				Action: SyncUpdate, Name: item.Name,
+				OldCommand: item.Command, NewCommand: command,
+				valueType: item.ValueType,
+			})
+		case !ok:
+			metadata, err := GetEntryMetadataTyped(item.Name, registryType)
+			if err == nil && metadata.Owner == owner {
+				plan.Steps = append(plan.Steps, SyncStep{Action: SyncRemove, Name: item.Name, OldCommand: item.Command})
+			}
+		}
+	}
+
+	for name, command := range wanted {
+		if _, ok := current[name]; !ok {
+			plan.Steps = append(plan.Steps, SyncStep{Action: SyncAdd, Name: name, NewCommand: command, valueType: registry.SZ})
+		}
+	}
+
+	sort.Slice(plan.Steps, func(i, j int) bool { return plan.Steps[i].Name < plan.Steps[j].Name })
+
+	return plan, nil
+}
+
+// ApplyPlan carries out the steps of a plan made by PlanSync. Added and updated
+// entries are recorded as owned by the plan's owner, removed ones lose their
+// metadata. It stops at the first failing step
+func ApplyPlan(plan SyncPlan, opts ...ApplyOption) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	options := applyOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.staleCheck {
+		lastWrite, fingerprint, _, err := readLocationState(plan.Location)
+		if err != nil {
+			return err
+		}
+		if !lastWrite.Equal(plan.lastWrite) && fingerprint != plan.fingerprint {
+			return ErrStalePlan
+		}
+	}
+
+	for _, step := range plan.Steps {
+		switch step.Action {
+		case SyncAdd, SyncUpdate:
+			if err := writeStringValue(step.Name, step.NewCommand, step.valueType, plan.Location); err != nil {
+				return err
+			}
+
+			metadata, err := GetEntryMetadataTyped(step.Name, plan.Location)
+			if err != nil && !errors.Is(err, ErrNoMetadata) {
+				return err
+			}
+			if metadata.Owner != plan.Owner {
+				metadata.Owner = plan.Owner
+				if metadata.CreatedAt.IsZero() {
+					metadata.CreatedAt = time.Now().UTC()
+				}
+				if err := SetEntryMetadata(step.Name, plan.Location, metadata); err != nil {
+					return err
+				}
+			}
+		case SyncRemove:
+			if err := RemoveStartupEntry(step.Name, plan.Location); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readLocationState reads the last-write time of a location's key, a fingerprint
+// of its values and the values themselves. A missing key has a zero time and no items
+func readLocationState(registryType StartupRegistryType) (time.Time, string, []StartupItem, error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	var lastWrite time.Time
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	switch {
+	case err == nil:
+		info, err := k.Stat()
+		k.Close()
+		if err != nil {
+			return time.Time{}, "", nil, fmt.Errorf("failed to stat registry key: %w", err)
+		}
+		lastWrite = info.ModTime()
+	case !errors.Is(err, registry.ErrNotExist):
+		return time.Time{}, "", nil, fmt.Errorf("failed to open registry key: %w", err)
+	}
+
+	items, err := listStartupItems(registryType)
+	if err != nil {
+		return time.Time{}, "", nil, err
+	}
+
+	// The value type is part of the state, a switch to REG_EXPAND_SZ is a change
+	values := make(map[string]string)
+	for _, item := range items {
+		values[item.Name] = fmt.Sprintf("%d:%s", item.ValueType, item.Command)
+	}
+	fingerprint := Snapshot{Entries: map[StartupRegistryType]map[string]string{registryType: values}}.Fingerprint()
+
+	return lastWrite, fingerprint, items, nil
+}
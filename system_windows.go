@@ -0,0 +1,72 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// systemSubdirectories are the directories below %WINDIR% that only the system
+// and administrators can write to. Others, such as Temp, Tasks or Tracing, accept
+// files from standard users and prove nothing about where an executable came from
+var systemSubdirectories = []string{"System32", "SysWOW64", "WinSxS", "SystemApps"}
+
+// microsoftSigners are the subject names Microsoft signs Windows components and
+// its own software with
+var microsoftSigners = map[string]bool{
+	"Microsoft Windows":     true,
+	"Microsoft Corporation": true,
+}
+
+// IsSystemEntry reports whether an entry launches a Windows component: an
+// executable located directly in the Windows directory or in one of its
+// protected subdirectories such as System32, or one carrying a valid embedded
+// signature from Microsoft. Environment variables in the command are expanded
+// before the executable is resolved
+func IsSystemEntry(entry StartupEntry) (bool, error) {
+	executable := CommandExecutable(entry.Command)
+
+	fullPath, err := filepath.Abs(executable)
+	if err != nil {
+		return false, fmt.Errorf("invalid command path: %w", err)
+	}
+	if _, err := os.Stat(fullPath); err != nil {
+		return false, fmt.Errorf("failed to resolve executable: %w", err)
+	}
+
+	// Only the protected parts of %WINDIR% belong to the OS
+	windowsDir, err := windows.GetSystemWindowsDirectory()
+	if err != nil {
+		return false, fmt.Errorf("failed to get windows directory: %w", err)
+	}
+	if strings.EqualFold(filepath.Dir(fullPath), filepath.Clean(windowsDir)) {
+		return true, nil
+	}
+	for _, dir := range systemSubdirectories {
+		if isUnderDirectory(fullPath, filepath.Join(windowsDir, dir)) {
+			return true, nil
+		}
+	}
+
+	// Otherwise require a valid signature issued to Microsoft
+	if verifySignature(fullPath) != nil {
+		return false, nil
+	}
+	signer, err := signerName(fullPath)
+	if err != nil {
+		return false, nil
+	}
+
+	return microsoftSigners[signer], nil
+}
+
+// isUnderDirectory reports whether path is inside dir, comparing case-insensitively
+func isUnderDirectory(path, dir string) bool {
+	dir = strings.TrimRight(filepath.Clean(dir), `\`) + `\`
+	return strings.HasPrefix(strings.ToLower(filepath.Clean(path)), strings.ToLower(dir))
+}
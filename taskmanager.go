@@ -0,0 +1,22 @@
+package winstartupreg
+
+// firstStartupTabBuild is the build of Windows 8, the first with a Startup tab in
+// Task Manager. Earlier versions listed startup programs in msconfig
+const firstStartupTabBuild = 9200
+
+// ImpactNotMeasured is the Impact of every TaskManagerItem. Task Manager derives
+// startup impact from boot performance traces, which the registry does not expose
+const ImpactNotMeasured = "Not measured"
+
+// TaskManagerItem is a startup program as the Startup tab of Task Manager shows it
+type TaskManagerItem struct {
+	Name        string              // Value or file name the item is stored under
+	DisplayName string              // Shown name: the file description of the executable, else Name
+	Publisher   string              // Company name from the executable's version information
+	Command     string              // Stored command, or the file path for Startup folder items
+	Source      StartupSource       // Kind of place the item was found in
+	Location    StartupRegistryType // Registry location, for SourceRegistry items
+	View        RegistryView        // Registry view, for SourceRegistry items
+	Enabled     bool                // Status column: Enabled or Disabled
+	Impact      string              // Startup impact column, see ImpactNotMeasured
+}
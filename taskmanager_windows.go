@@ -0,0 +1,145 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// approvedKey is the parent of the StartupApproved keys of HKCU and HKLM
+const approvedKey = `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved`
+
+// ListAsTaskManager lists the startup programs the way the Startup tab of Task
+// Manager on this machine shows them. From Windows 8 through Windows 11 the tab
+// shows the same sources: the HKCU and HKLM Run keys and both Startup folders,
+// but neither RunOnce nor the policy keys. Entries in the 32-bit view of HKLM are
+// switched through StartupApproved\Run32 and Startup folder items through
+// StartupApproved\StartupFolder. Packaged apps with a startup task, which
+// Windows 10 and later also show, are not included. Windows versions before
+// Windows 8 have no Startup tab and are rejected
+func ListAsTaskManager() ([]TaskManagerItem, error) {
+	if build := windows.RtlGetVersion().BuildNumber; build < firstStartupTabBuild {
+		return nil, fmt.Errorf("build %d has no Task Manager Startup tab", build)
+	}
+
+	var items []TaskManagerItem
+
+	// Each Run key with the StartupApproved key that holds its enabled states
+	type runSource struct {
+		registryType StartupRegistryType
+		view         RegistryView
+		approval     string
+	}
+	sources := []runSource{
+		{CurrentUserRun, DefaultView, "Run"},
+		{AllUsersRun, View64, "Run"},
+	}
+	if is64BitWindows() {
+		sources = append(sources, runSource{AllUsersRun, View32, "Run32"})
+	}
+
+	for _, source := range sources {
+		registryItems, err := listStartupItemsInView(source.registryType, source.view)
+		if err != nil {
+			return nil, err
+		}
+
+		_, rootKey := getRegistryPath(source.registryType)
+		for _, item := range registryItems {
+			blob, err := readApprovalValue(rootKey, approvedKey+`\`+source.approval, item.Name)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, newTaskManagerItem(item, CommandExecutable(item.Command), approvalBlobEnabled(blob)))
+		}
+	}
+
+	for _, folder := range []struct {
+		id     *windows.KNOWNFOLDERID
+		source StartupSource
+		root   registry.Key
+	}{
+		{windows.FOLDERID_Startup, SourceUserStartupFolder, registry.CURRENT_USER},
+		{windows.FOLDERID_CommonStartup, SourceCommonStartupFolder, registry.LOCAL_MACHINE},
+	} {
+		folderItems, err := listStartupFolderItems(folder.id, folder.source)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range folderItems {
+			blob, err := readApprovalValue(folder.root, approvedKey+`\StartupFolder`, item.Name)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, newTaskManagerItem(item, item.Command, approvalBlobEnabled(blob)))
+		}
+	}
+
+	return items, nil
+}
+
+// newTaskManagerItem fills in the columns Task Manager derives from the executable
+func newTaskManagerItem(item StartupItem, executable string, enabled bool) TaskManagerItem {
+	tmItem := TaskManagerItem{
+		Name:        item.Name,
+		DisplayName: item.Name,
+		Command:     item.Command,
+		Source:      item.Source,
+		Location:    item.Location,
+		View:        item.View,
+		Enabled:     enabled,
+		Impact:      ImpactNotMeasured,
+	}
+
+	// Shortcuts are shown by their name without the extension
+	if item.Source != SourceRegistry {
+		tmItem.DisplayName = strings.TrimSuffix(item.Name, filepath.Ext(item.Name))
+	}
+
+	description, company := readVersionStrings(executable)
+	if description != "" {
+		tmItem.DisplayName = description
+	}
+	tmItem.Publisher = company
+
+	return tmItem
+}
+
+// readVersionStrings returns the FileDescription and CompanyName of a file's
+// version resource, empty when the file has none
+func readVersionStrings(path string) (description, company string) {
+	size, err := windows.GetFileVersionInfoSize(path, nil)
+	if err != nil || size == 0 {
+		return "", ""
+	}
+	info := make([]byte, size)
+	if err := windows.GetFileVersionInfo(path, 0, size, unsafe.Pointer(&info[0])); err != nil {
+		return "", ""
+	}
+
+	// Use the first language and code page the resource lists
+	var translation *[2]uint16
+	var n uint32
+	if err := windows.VerQueryValue(unsafe.Pointer(&info[0]), `\VarFileInfo\Translation`, unsafe.Pointer(&translation), &n); err != nil || n < 4 {
+		return "", ""
+	}
+	prefix := fmt.Sprintf(`\StringFileInfo\%04x%04x\`, translation[0], translation[1])
+
+	query := func(name string) string {
+		var value *uint16
+		var length uint32
+		if err := windows.VerQueryValue(unsafe.Pointer(&info[0]), prefix+name, unsafe.Pointer(&value), &length); err != nil || length == 0 {
+			return ""
+		}
+		return strings.TrimSpace(windows.UTF16PtrToString(value))
+	}
+
+	return query("FileDescription"), query("CompanyName")
+}
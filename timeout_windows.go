@@ -0,0 +1,86 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// ListAllStartupEntriesTimeout reads every known location like ListAllStartupEntries,
+// but gives each location at most perLocation to answer. The locations are read
+// in parallel and the call returns once all have answered or timed out, with the
+// entries of those that answered. A location that failed or timed out has its
+// error, wrapping ErrLocationTimeout for a timeout, in locationErrs. A missing key
+// is not an error. A read that stalls cannot be cancelled and finishes in the
+// background, its result is discarded
+func ListAllStartupEntriesTimeout(perLocation time.Duration) (entries map[StartupRegistryType]map[string]string, locationErrs map[StartupRegistryType]error, err error) {
+	if perLocation <= 0 {
+		return nil, nil, fmt.Errorf("timeout must be positive, got %s", perLocation)
+	}
+
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	type result struct {
+		entries map[string]string
+		err     error
+	}
+
+	// Buffered so a read that outlives its timeout never blocks
+	results := make([]chan result, len(registryTypes))
+	for i, registryType := range registryTypes {
+		results[i] = make(chan result, 1)
+		go func(registryType StartupRegistryType, out chan<- result) {
+			locationEntries, err := ListStartupEntries(registryType)
+			out <- result{locationEntries, err}
+		}(registryType, results[i])
+	}
+
+	entries = make(map[StartupRegistryType]map[string]string)
+	locationErrs = make(map[StartupRegistryType]error)
+	deadline := time.NewTimer(perLocation)
+	defer deadline.Stop()
+
+	timedOut := false
+	for i, registryType := range registryTypes {
+		// Every location started at the same time, so once the deadline has passed
+		// only the ones that already answered are collected
+		var r result
+		if timedOut {
+			select {
+			case r = <-results[i]:
+			default:
+				locationErrs[registryType] = fmt.Errorf("failed to read %s within %s: %w", registryType, perLocation, ErrLocationTimeout)
+				continue
+			}
+		} else {
+			select {
+			case r = <-results[i]:
+			case <-deadline.C:
+				timedOut = true
+				locationErrs[registryType] = fmt.Errorf("failed to read %s within %s: %w", registryType, perLocation, ErrLocationTimeout)
+				continue
+			}
+		}
+
+		switch {
+		case errors.Is(r.err, registry.ErrNotExist):
+			// A missing key simply has no entries
+		case r.err != nil:
+			locationErrs[registryType] = r.err
+		case len(r.entries) > 0:
+			entries[registryType] = r.entries
+		}
+	}
+
+	return entries, locationErrs, nil
+}
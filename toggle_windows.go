@@ -0,0 +1,164 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DisableStartupEntry switches an entry off through its StartupApproved blob, the
+// way Task Manager does, leaving the Run value in place. Only the state bit of an
+// existing blob is changed, so whatever else Windows stored there is preserved
+func DisableStartupEntry(name string, registryType StartupRegistryType) error {
+	return setStartupEntryEnabled(name, registryType, false)
+}
+
+// EnableStartupEntry switches an entry disabled through StartupApproved back on
+func EnableStartupEntry(name string, registryType StartupRegistryType) error {
+	return setStartupEntryEnabled(name, registryType, true)
+}
+
+// IsStartupEntryEnabled reads the StartupApproved state of an entry. An entry
+// without a blob has never been switched off and counts as enabled
+func IsStartupEntryEnabled(name string, registryType StartupRegistryType) (bool, error) {
+	if _, _, ok := getApprovalPath(registryType); !ok {
+		return false, fmt.Errorf("location %s has no approval state", registryType)
+	}
+
+	present, err := EntryExists(name, registryType)
+	if err != nil {
+		return false, err
+	}
+	if !present {
+		keyPath, _ := getRegistryPath(registryType)
+		return false, fmt.Errorf("startup entry '%s' not found in %s: %w", name, keyPath, ErrEntryNotFound)
+	}
+
+	return readApprovalState(name, registryType)
+}
+
+// setStartupEntryEnabled flips the state bit of the StartupApproved blob of an
+// entry. The blob of an all-users entry lives under HKLM and needs elevation
+func setStartupEntryEnabled(name string, registryType StartupRegistryType, enabled bool) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if _, _, ok := getApprovalPath(registryType); !ok {
+		return fmt.Errorf("location %s has no approval state", registryType)
+	}
+	if err := requireElevation(registryType); err != nil {
+		return err
+	}
+
+	// Only entries that exist can be switched
+	present, err := EntryExists(name, registryType)
+	if err != nil {
+		return err
+	}
+	if !present {
+		keyPath, _ := getRegistryPath(registryType)
+		return fmt.Errorf("startup entry '%s' not found in %s: %w", name, keyPath, ErrEntryNotFound)
+	}
+
+	blob, err := readApprovalBlob(name, registryType)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to write when the state already matches
+	wasEnabled := approvalBlobEnabled(blob)
+	if len(blob) > 0 && wasEnabled == enabled {
+		return nil
+	}
+
+	journal, err := openJournal()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	if err := writeApprovalBlob(name, registryType, setApprovalBlobEnabled(blob, enabled, time.Now())); err != nil {
+		return err
+	}
+
+	operation := JournalDisable
+	if enabled {
+		operation = JournalEnable
+	}
+	return journal.record(JournalEntry{
+		Operation: operation,
+		Location:  registryType,
+		Name:      name,
+		Before:    enabledState(wasEnabled),
+		After:     enabledState(enabled),
+	})
+}
+
+// enabledState renders an approval state for the journal
+func enabledState(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// AddStartupEntryEnabledAtomic adds an entry and marks it enabled in StartupApproved
+// in one step, so a stale disabled blob left by an earlier install cannot switch
+// the new entry off. The blob is prepared before anything is written and stored
+// right after the Run value. If storing the blob fails the Run value is rolled
+// back, restoring the previous command when the entry already existed
+func AddStartupEntryEnabledAtomic(entry StartupEntry, registryType StartupRegistryType) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if _, _, ok := getApprovalPath(registryType); !ok {
+		return fmt.Errorf("location %s has no approval state", registryType)
+	}
+	if err := requireElevation(registryType); err != nil {
+		return err
+	}
+
+	// Validate input
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	// Remember the current value for the rollback
+	present, err := EntryExists(entry.Name, registryType)
+	if err != nil {
+		return err
+	}
+	var previous QuarantinedEntry
+	if present {
+		if previous, err = captureEntry(entry.Name, registryType); err != nil {
+			return err
+		}
+	}
+
+	blob, err := readApprovalBlob(entry.Name, registryType)
+	if err != nil {
+		return err
+	}
+	blob = setApprovalBlobEnabled(blob, true, time.Now())
+
+	if err := AddStartupEntry(entry, registryType); err != nil {
+		return err
+	}
+
+	if err := writeApprovalBlob(entry.Name, registryType, blob); err != nil {
+		var rollbackErr error
+		if present {
+			rollbackErr = writeStringValue(previous.Name, previous.Command, previous.ValueType, registryType)
+		} else {
+			rollbackErr = RemoveStartupEntry(entry.Name, registryType, WithForce())
+		}
+		if rollbackErr != nil {
+			return fmt.Errorf("failed to enable startup entry '%s' and to roll it back: %w", entry.Name, errors.Join(err, rollbackErr))
+		}
+		return fmt.Errorf("failed to enable startup entry '%s', rolled back: %w", entry.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package winstartupreg
+
+import (
+	"os"
+	"strings"
+)
+
+// userSpecificVariables are the variables whose value depends on the user. When
+// one of them is missing from a user's environment it stays unexpanded rather
+// than taking the value of the calling process
+var userSpecificVariables = map[string]bool{
+	"APPDATA":      true,
+	"HOMEDRIVE":    true,
+	"HOMEPATH":     true,
+	"HOMESHARE":    true,
+	"LOCALAPPDATA": true,
+	"LOGONSERVER":  true,
+	"ONEDRIVE":     true,
+	"TEMP":         true,
+	"TMP":          true,
+	"USERDOMAIN":   true,
+	"USERNAME":     true,
+	"USERPROFILE":  true,
+}
+
+// userEnvironment holds the variables of one user keyed by upper-case name, since
+// Windows compares variable names case-insensitively
+type userEnvironment map[string]string
+
+// lookup resolves a variable from the environment. Machine-wide variables it does
+// not hold, such as SystemRoot or ProgramFiles, are the same for every user and
+// are taken from the calling process
+func (e userEnvironment) lookup(name string) (string, bool) {
+	key := strings.ToUpper(name)
+	if value, ok := e[key]; ok {
+		return value, true
+	}
+	if userSpecificVariables[key] {
+		return "", false
+	}
+	return os.LookupEnv(name)
+}
+
+// set stores a variable, expanding references to variables defined so far when
+// the value came from a REG_EXPAND_SZ. A user Path is appended to the system one
+// the way Windows builds the logon environment
+func (e userEnvironment) set(name, value string, expand bool) {
+	if expand {
+		value = expandVariables(value, e.lookup)
+	}
+
+	key := strings.ToUpper(name)
+	if system, ok := e[key]; ok && key == "PATH" && system != "" && value != "" {
+		value = strings.TrimSuffix(system, ";") + ";" + value
+	}
+	e[key] = value
+}
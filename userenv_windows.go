@@ -0,0 +1,103 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// ExpandForUser expands the %NAME% references of a command with the environment
+// of the user identified by sid instead of the calling process. The environment
+// is rebuilt the way Windows builds it at logon: the system variables, the user's
+// profile directory and shell folders, the volatile variables of the session and
+// the user's own Environment key, with the user Path appended to the system one.
+// The user's hive must be loaded under HKEY_USERS, which is the case while the
+// user is logged on. References that cannot be resolved are kept literally
+func ExpandForUser(command string, sid string) (string, error) {
+	env, err := loadUserEnvironment(sid)
+	if err != nil {
+		return "", err
+	}
+
+	return expandVariables(command, env.lookup), nil
+}
+
+// loadUserEnvironment rebuilds the environment of the user whose hive is loaded under sid
+func loadUserEnvironment(sid string) (userEnvironment, error) {
+	// Validate input
+	hive, err := registry.OpenKey(registry.USERS, sid, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, fmt.Errorf("hive of user %s is not loaded", sid)
+		}
+		return nil, fmt.Errorf("failed to open user hive: %w", err)
+	}
+	hive.Close()
+
+	env := userEnvironment{}
+
+	// System variables first, everything per user overrides them
+	if err := readEnvironmentKey(env, registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`, nil); err != nil {
+		return nil, err
+	}
+
+	// The profile directory comes from the machine-wide profile list
+	profileKey := `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList\` + sid
+	if err := readEnvironmentKey(env, registry.LOCAL_MACHINE, profileKey, map[string]string{"ProfileImagePath": "USERPROFILE"}); err != nil {
+		return nil, err
+	}
+
+	// Shell folders are stored relative to %USERPROFILE%
+	shellFolders := map[string]string{"AppData": "APPDATA", "Local AppData": "LOCALAPPDATA"}
+	if err := readEnvironmentKey(env, registry.USERS, sid+`\Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`, shellFolders); err != nil {
+		return nil, err
+	}
+
+	// Variables of the logon session, then the user's own ones
+	for _, keyPath := range []string{`Volatile Environment`, `Environment`} {
+		if err := readEnvironmentKey(env, registry.USERS, sid+`\`+keyPath, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return env, nil
+}
+
+// readEnvironmentKey copies the string values of a key into env. With names set,
+// only the listed values are copied, under the variable name they map to. A
+// missing key is skipped
+func readEnvironmentKey(env userEnvironment, rootKey registry.Key, keyPath string, names map[string]string) error {
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open environment key: %w", err)
+	}
+	defer k.Close()
+
+	valueNames, err := k.ReadValueNames(0)
+	if err != nil {
+		return fmt.Errorf("failed to read value names: %w", err)
+	}
+
+	for _, name := range valueNames {
+		variable := name
+		if names != nil {
+			if variable = names[name]; variable == "" {
+				continue
+			}
+		}
+
+		value, valType, err := k.GetStringValue(name)
+		if err != nil {
+			continue
+		}
+		env.set(variable, value, valType == registry.EXPAND_SZ)
+	}
+
+	return nil
+}
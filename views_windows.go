@@ -0,0 +1,67 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// FindCrossViewDuplicates reads both views of the HKLM Run and RunOnce keys and
+// reports every value name present in both, with the command of each view. The
+// names are compared case-insensitively like the registry does. 32-bit Windows
+// has a single view and never reports duplicates
+func FindCrossViewDuplicates() ([]CrossViewDuplicate, error) {
+	if !is64BitWindows() {
+		return nil, nil
+	}
+
+	var duplicates []CrossViewDuplicate
+	for _, registryType := range []StartupRegistryType{AllUsersRun, AllUsersRunOnce} {
+		items64, err := listStartupItemsInView(registryType, View64)
+		if err != nil {
+			return nil, err
+		}
+		items32, err := listStartupItemsInView(registryType, View32)
+		if err != nil {
+			return nil, err
+		}
+
+		byName := make(map[string]StartupItem)
+		for _, item := range items32 {
+			byName[strings.ToLower(item.Name)] = item
+		}
+
+		// items64 is sorted by name, which keeps the result sorted too
+		for _, item := range items64 {
+			if twin, ok := byName[strings.ToLower(item.Name)]; ok {
+				duplicates = append(duplicates, CrossViewDuplicate{
+					Location:  registryType,
+					Name:      item.Name,
+					Command64: item.Command,
+					Command32: twin.Command,
+				})
+			}
+		}
+	}
+
+	return duplicates, nil
+}
+
+// is64BitWindows reports whether the OS is 64-bit and so has separate 32-bit
+// and 64-bit views of HKLM\SOFTWARE
+func is64BitWindows() bool {
+	switch runtime.GOARCH {
+	case "amd64", "arm64":
+		return true
+	}
+
+	// A 32-bit process runs under WOW64 only on 64-bit Windows
+	var wow64 bool
+	if err := windows.IsWow64Process(windows.CurrentProcess(), &wow64); err != nil {
+		return false
+	}
+	return wow64
+}
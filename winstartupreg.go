@@ -1,14 +1,69 @@
 package winstartupreg
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
-
-	"golang.org/x/sys/windows/registry"
+	"sync/atomic"
 )
 
+// ErrUnsupportedPlatform is returned by every operation when the package is used on a non-Windows OS
+var ErrUnsupportedPlatform = errors.New("winstartupreg: unsupported platform, Windows is required")
+
+// ErrLocationTimeout is reported for a startup location that did not answer within the allowed time
+var ErrLocationTimeout = errors.New("winstartupreg: timed out reading startup location")
+
+// ErrStalePlan is returned by ApplyPlan when the location changed after the plan was made
+var ErrStalePlan = errors.New("winstartupreg: startup location changed since the plan was made")
+
+// ErrReadOnlyMode is returned by every operation that would modify the registry while SetReadOnly is in effect
+var ErrReadOnlyMode = errors.New("winstartupreg: read-only mode, modifications are disabled")
+
+// ErrEntryPinned is returned when removing a pinned entry without WithForce
+var ErrEntryPinned = errors.New("winstartupreg: entry is pinned")
+
+// ErrEntryNotFound is returned when a startup entry does not exist in the location asked for
+var ErrEntryNotFound = errors.New("winstartupreg: startup entry not found")
+
+// ErrKeyNotFound is returned when the registry key of a startup location does not exist
+var ErrKeyNotFound = errors.New("winstartupreg: registry key not found")
+
+// ErrAccessDenied is returned when Windows refuses access to a startup location,
+// typically an all-users location without elevation
+var ErrAccessDenied = errors.New("winstartupreg: access denied")
+
+// ErrElevationRequired is returned before writing an all-users location from a
+// process that is not elevated. Errors wrapping it also match ErrAccessDenied
+var ErrElevationRequired = errors.New("winstartupreg: elevation required")
+
+// ErrDependencyCycle is returned by ListRunOnceEx when RunOnceEx keys depend on
+// each other through their Depend subkeys, so no execution order exists
+var ErrDependencyCycle = errors.New("winstartupreg: RunOnceEx dependency cycle")
+
+// readOnly is set by SetReadOnly
+var readOnly atomic.Bool
+
+// SetReadOnly switches the package into or out of audit-only mode. While it is
+// on, every function that would add, change or remove an entry, its approval
+// state or its metadata returns ErrReadOnlyMode before touching the registry.
+// Listing, diagnostics and dry runs keep working
+func SetReadOnly(enabled bool) {
+	readOnly.Store(enabled)
+}
+
+// IsReadOnly reports whether SetReadOnly is in effect
+func IsReadOnly() bool {
+	return readOnly.Load()
+}
+
+// checkWritable fails with ErrReadOnlyMode while SetReadOnly is in effect
+func checkWritable() error {
+	if readOnly.Load() {
+		return ErrReadOnlyMode
+	}
+	return nil
+}
+
 // StartupRegistryType represents different startup registry locations
 type StartupRegistryType int
 
@@ -17,174 +72,172 @@ const (
 	CurrentUserRunOnce
 	AllUsersRun
 	AllUsersRunOnce
+	// Policies\Explorer\Run keys, whose value names are the launch ordinals "1", "2", ...
+	CurrentUserPolicyRun
+	AllUsersPolicyRun
 )
 
 // StartupEntry represents a Windows startup registry entry
 type StartupEntry struct {
 	Name    string
 	Command string
-}
 
-// getRegistryPath returns the full registry path and root key for a given startup type
-func getRegistryPath(registryType StartupRegistryType) (string, registry.Key) {
-	switch registryType {
-	case CurrentUserRun:
-		return `Software\Microsoft\Windows\CurrentVersion\Run`, registry.CURRENT_USER
-	case CurrentUserRunOnce:
-		return `Software\Microsoft\Windows\CurrentVersion\RunOnce`, registry.CURRENT_USER
-	case AllUsersRun:
-		return `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, registry.LOCAL_MACHINE
-	case AllUsersRunOnce:
-		return `SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnce`, registry.LOCAL_MACHINE
-	default:
-		return `Software\Microsoft\Windows\CurrentVersion\Run`, registry.CURRENT_USER
-	}
+	// Args are passed to the executable named by Command. They are quoted as
+	// needed when the value is stored, so Command holds only the executable
+	Args []string
 }
 
-// AddStartupEntry adds an application to Windows startup registry
-func AddStartupEntry(entry StartupEntry, registryType StartupRegistryType) error {
-	// Validate input
-	if entry.Name == "" {
+// maxValueNameLength is the longest registry value name Windows accepts, in characters
+const maxValueNameLength = 16383
+
+// Validate checks that the entry can be stored as a registry value: the name must
+// be non-empty and within the registry's length limit, and neither the name, the
+// command nor the arguments may contain NUL characters. It does not check that the
+// executable exists
+func (e StartupEntry) Validate() error {
+	if e.Name == "" {
 		return fmt.Errorf("entry name cannot be empty")
 	}
-
-	// Normalize and validate command path
-	fullPath, err := filepath.Abs(entry.Command)
-	if err != nil {
-		return fmt.Errorf("invalid command path: %w", err)
+	if len([]rune(e.Name)) > maxValueNameLength {
+		return fmt.Errorf("entry name is longer than %d characters", maxValueNameLength)
 	}
-
-	// Check if the executable exists
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		return fmt.Errorf("executable does not exist: %s", fullPath)
+	if strings.Contains(e.Name, "\x00") {
+		return fmt.Errorf("entry name cannot contain NUL characters")
 	}
-
-	// Get registry path and root key
-	keyPath, rootKey := getRegistryPath(registryType)
-
-	// Open the registry key with write access
-	k, err := registry.OpenKey(rootKey, keyPath, registry.ALL_ACCESS)
-	if err != nil {
-		return fmt.Errorf("failed to open registry key: %w", err)
+	if e.Command == "" {
+		return fmt.Errorf("command cannot be empty")
 	}
-	defer k.Close()
-
-	// Set the registry value
-	err = k.SetStringValue(entry.Name, fullPath)
-	if err != nil {
-		return fmt.Errorf("failed to set registry value: %w", err)
+	if strings.Contains(e.Command, "\x00") {
+		return fmt.Errorf("command cannot contain NUL characters")
+	}
+	for _, arg := range e.Args {
+		if strings.Contains(arg, "\x00") {
+			return fmt.Errorf("arguments cannot contain NUL characters")
+		}
 	}
-
 	return nil
 }
 
-// RemoveStartupEntry removes an application from Windows startup registry
-func RemoveStartupEntry(entryName string, registryType StartupRegistryType) error {
-	// Get registry path and root key
-	keyPath, rootKey := getRegistryPath(registryType)
-
-	// Attempt to open the registry key with write access
-	k, err := registry.OpenKey(rootKey, keyPath, registry.ALL_ACCESS)
-	if err != nil {
-		return fmt.Errorf("failed to open registry key: %w", err)
+// String returns the name of the registry location constant, e.g. "CurrentUserRun"
+func (t StartupRegistryType) String() string {
+	switch t {
+	case CurrentUserRun:
+		return "CurrentUserRun"
+	case CurrentUserRunOnce:
+		return "CurrentUserRunOnce"
+	case AllUsersRun:
+		return "AllUsersRun"
+	case AllUsersRunOnce:
+		return "AllUsersRunOnce"
+	case CurrentUserPolicyRun:
+		return "CurrentUserPolicyRun"
+	case AllUsersPolicyRun:
+		return "AllUsersPolicyRun"
+	default:
+		return fmt.Sprintf("StartupRegistryType(%d)", int(t))
 	}
-	defer k.Close()
+}
 
-	// Attempt to delete the value
-	err = k.DeleteValue(entryName)
-	if err != nil {
-		// Check if the error indicates the value doesn't exist
-		if strings.Contains(err.Error(), "The system cannot find the file specified") {
-			return fmt.Errorf("startup entry '%s' not found in %s", entryName, keyPath)
-		}
-		return fmt.Errorf("failed to delete registry value: %w", err)
+// MarshalText encodes the location by name so persisted data survives a reordering of the constants
+func (t StartupRegistryType) MarshalText() ([]byte, error) {
+	name := t.String()
+	if _, ok := registryTypeNames[name]; !ok {
+		return nil, fmt.Errorf("unknown startup registry type %d", int(t))
 	}
+	return []byte(name), nil
+}
 
+// UnmarshalText decodes a location from the name produced by MarshalText
+func (t *StartupRegistryType) UnmarshalText(text []byte) error {
+	registryType, ok := registryTypeNames[string(text)]
+	if !ok {
+		return fmt.Errorf("unknown startup registry type %q", text)
+	}
+	*t = registryType
 	return nil
 }
 
-// SafeRemoveStartupEntry provides a comprehensive removal method
-func SafeRemoveStartupEntry(entryName string) error {
-	// List of registry types to check
-	registryTypes := []StartupRegistryType{
-		CurrentUserRun,
-		CurrentUserRunOnce,
-		AllUsersRun,
-		AllUsersRunOnce,
-	}
+// registryTypeNames maps the String form of every location back to its constant
+var registryTypeNames = map[string]StartupRegistryType{
+	CurrentUserRun.String():       CurrentUserRun,
+	CurrentUserRunOnce.String():   CurrentUserRunOnce,
+	AllUsersRun.String():          AllUsersRun,
+	AllUsersRunOnce.String():      AllUsersRunOnce,
+	CurrentUserPolicyRun.String(): CurrentUserPolicyRun,
+	AllUsersPolicyRun.String():    AllUsersPolicyRun,
+}
 
-	var lastErr error
-	var removedFromAny bool
+// AddOption configures AddStartupEntry
+type AddOption func(*addOptions)
 
-	// Try to remove from all possible locations
-	for _, registryType := range registryTypes {
-		err := RemoveStartupEntry(entryName, registryType)
-		if err == nil {
-			removedFromAny = true
-		} else {
-			lastErr = err
-		}
-	}
+type addOptions struct {
+	autoExpandType bool
+	expandType     bool
+	validatePE     bool
+}
 
-	if !removedFromAny {
-		return fmt.Errorf("failed to remove startup entry '%s' from any location: %w", entryName, lastErr)
+// WithAutoExpandType stores commands that reference an environment variable,
+// such as %LOCALAPPDATA%\App\app.exe, as REG_EXPAND_SZ so Windows expands them
+// at logon. The variables are expanded before checking that the executable
+// exists. A % that is not part of a defined %NAME% reference is taken literally
+// and on its own keeps the value REG_SZ, which is also the default without this option
+func WithAutoExpandType() AddOption {
+	return func(o *addOptions) {
+		o.autoExpandType = true
 	}
-
-	return nil
 }
 
-// ListStartupEntries retrieves startup entries from a specific registry location
-func ListStartupEntries(registryType StartupRegistryType) (map[string]string, error) {
-	// Get registry path and root key
-	keyPath, rootKey := getRegistryPath(registryType)
-
-	// Open the registry key with read access
-	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open registry key: %w", err)
+// WithExpandType always stores the command as REG_EXPAND_SZ, for example
+// %ProgramFiles%\App\app.exe, leaving it to Windows to expand the variables at
+// logon. They are expanded before checking that the executable exists
+func WithExpandType() AddOption {
+	return func(o *addOptions) {
+		o.expandType = true
 	}
-	defer k.Close()
+}
 
-	// Get all value names
-	valueNames, err := k.ReadValueNames(0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read value names: %w", err)
+// WithPEValidation rejects an executable that is not a PE image, such as a text
+// file or a truncated download, instead of only checking that the file exists.
+// Leave it out to register a .bat, .cmd or other script directly
+func WithPEValidation() AddOption {
+	return func(o *addOptions) {
+		o.validatePE = true
 	}
+}
 
-	// Create a map to store startup entries
-	entries := make(map[string]string)
+// RemoveOption configures RemoveStartupEntry and SafeRemoveStartupEntry
+type RemoveOption func(*removeOptions)
 
-	// Read each value
-	for _, name := range valueNames {
-		value, _, err := k.GetStringValue(name)
-		if err == nil {
-			entries[name] = value
-		}
-	}
+type removeOptions struct {
+	force        bool
+	keepLauncher bool
+}
 
-	return entries, nil
+// WithForce removes an entry even when it is pinned with PinEntry
+func WithForce() RemoveOption {
+	return func(o *removeOptions) {
+		o.force = true
+	}
 }
 
-// ListAllStartupEntries retrieves startup entries from all known locations
-func ListAllStartupEntries() (map[StartupRegistryType]map[string]string, error) {
-	// List of registry types to check
-	registryTypes := []StartupRegistryType{
-		CurrentUserRun,
-		CurrentUserRunOnce,
-		AllUsersRun,
-		AllUsersRunOnce,
+// keepLauncher leaves the launcher script of an entry in place, for callers that
+// write the entry back later
+func keepLauncher() RemoveOption {
+	return func(o *removeOptions) {
+		o.keepLauncher = true
 	}
+}
 
-	// Map to store all startup entries
-	allEntries := make(map[StartupRegistryType]map[string]string)
+// RenameOption configures RenameStartupEntry
+type RenameOption func(*renameOptions)
 
-	// Retrieve entries from each location
-	for _, registryType := range registryTypes {
-		entries, err := ListStartupEntries(registryType)
-		if err == nil && len(entries) > 0 {
-			allEntries[registryType] = entries
-		}
-	}
+type renameOptions struct {
+	overwrite bool
+}
 
-	return allEntries, nil
+// WithOverwrite lets RenameStartupEntry replace an existing entry of the new name
+func WithOverwrite() RenameOption {
+	return func(o *renameOptions) {
+		o.overwrite = true
+	}
 }
@@ -0,0 +1,399 @@
+//go:build !windows
+
+package winstartupreg
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// AddStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupEntry(entry StartupEntry, registryType StartupRegistryType, opts ...AddOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// AddStartupEntryWithView is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupEntryWithView(entry StartupEntry, registryType StartupRegistryType, view RegistryView, opts ...AddOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// AddStartupEntryIfChanged is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupEntryIfChanged(entry StartupEntry, registryType StartupRegistryType, opts ...AddOption) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// RemoveStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func RemoveStartupEntry(entryName string, registryType StartupRegistryType, opts ...RemoveOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// RemoveStartupEntryWithView is not supported on this platform and returns ErrUnsupportedPlatform
+func RemoveStartupEntryWithView(entryName string, registryType StartupRegistryType, view RegistryView, opts ...RemoveOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// SafeRemoveStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func SafeRemoveStartupEntry(entryName string, opts ...RemoveOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// EnsureAbsent is not supported on this platform and returns ErrUnsupportedPlatform
+func EnsureAbsent(name string) (wasPresent bool, err error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// ListStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func ListStartupEntries(registryType StartupRegistryType) (map[string]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ListStartupEntriesByType is not supported on this platform and returns ErrUnsupportedPlatform
+func ListStartupEntriesByType(registryType StartupRegistryType, valueType uint32) (map[string]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ListAllStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func ListAllStartupEntries() (map[StartupRegistryType]map[string]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ListLogonScheduledTasks is not supported on this platform and returns ErrUnsupportedPlatform
+func ListLogonScheduledTasks() ([]ScheduledTask, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// AddStartupEntryAt is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupEntryAt(entry StartupEntry, index int, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// ReorderStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func ReorderStartupEntries(registryType StartupRegistryType, order []string) error {
+	return ErrUnsupportedPlatform
+}
+
+// IsSystemEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func IsSystemEntry(entry StartupEntry) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// DiagnoseStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func DiagnoseStartupEntries() ([]StartupDiagnostic, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// QuarantineToFile is not supported on this platform and returns ErrUnsupportedPlatform
+func QuarantineToFile(names []string, path string) error {
+	return ErrUnsupportedPlatform
+}
+
+// RestoreFromQuarantineFile is not supported on this platform and returns ErrUnsupportedPlatform
+func RestoreFromQuarantineFile(path string) error {
+	return ErrUnsupportedPlatform
+}
+
+// ListStartupItems is not supported on this platform and returns ErrUnsupportedPlatform
+func ListStartupItems(registryType StartupRegistryType) ([]StartupItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ListStartupItemsInView is not supported on this platform and returns ErrUnsupportedPlatform
+func ListStartupItemsInView(registryType StartupRegistryType, view RegistryView) ([]StartupItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ListEnforcedStartupItems is not supported on this platform and returns ErrUnsupportedPlatform
+func ListEnforcedStartupItems() ([]StartupItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// RequiresElevation is not supported on this platform and returns ErrUnsupportedPlatform
+func RequiresElevation(entry StartupEntry) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// EntryExists is not supported on this platform and returns ErrUnsupportedPlatform
+func EntryExists(name string, registryType StartupRegistryType) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// GetStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func GetStartupEntry(name string, registryType StartupRegistryType) (entry StartupEntry, found bool, err error) {
+	return StartupEntry{}, false, ErrUnsupportedPlatform
+}
+
+// GetStartupEntryCanonical is not supported on this platform and returns ErrUnsupportedPlatform
+func GetStartupEntryCanonical(name string, registryType StartupRegistryType) (raw, canonical string, err error) {
+	return "", "", ErrUnsupportedPlatform
+}
+
+// ListEffectiveStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func ListEffectiveStartupEntries() ([]EffectiveEntry, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// CompareAndSwap is not supported on this platform and returns ErrUnsupportedPlatform
+func CompareAndSwap(name, oldCommand, newCommand string, registryType StartupRegistryType) (swapped bool, err error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// GetEntryMetadataTyped is not supported on this platform and returns ErrUnsupportedPlatform
+func GetEntryMetadataTyped(name string, registryType StartupRegistryType) (EntryMetadata, error) {
+	return EntryMetadata{}, ErrUnsupportedPlatform
+}
+
+// SetEntryMetadata is not supported on this platform and returns ErrUnsupportedPlatform
+func SetEntryMetadata(name string, registryType StartupRegistryType, metadata EntryMetadata) error {
+	return ErrUnsupportedPlatform
+}
+
+// TouchSelfEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func TouchSelfEntry(name string) (updated bool, err error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// SelfRepair is not supported on this platform and returns ErrUnsupportedPlatform
+func SelfRepair(owner string, opts ...RepairOption) ([]Change, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// SummarizeStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func SummarizeStartupEntries() (map[StartupRegistryType][]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// AddNoWindowStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func AddNoWindowStartupEntry(entry StartupEntry, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// DiffRegFile is not supported on this platform and returns ErrUnsupportedPlatform
+func DiffRegFile(r io.Reader) (SnapshotDiff, error) {
+	return SnapshotDiff{}, ErrUnsupportedPlatform
+}
+
+// DisableStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func DisableStartupEntry(name string, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// EnableStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func EnableStartupEntry(name string, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// IsStartupEntryEnabled is not supported on this platform and returns ErrUnsupportedPlatform
+func IsStartupEntryEnabled(name string, registryType StartupRegistryType) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// ListEverything is not supported on this platform and returns ErrUnsupportedPlatform
+func ListEverything() ([]StartupItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// RepairMalformedCommands is not supported on this platform and returns ErrUnsupportedPlatform
+func RepairMalformedCommands(dryRun bool) ([]Change, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ListAllStartupEntriesTimeout is not supported on this platform and returns ErrUnsupportedPlatform
+func ListAllStartupEntriesTimeout(perLocation time.Duration) (entries map[StartupRegistryType]map[string]string, locationErrs map[StartupRegistryType]error, err error) {
+	return nil, nil, ErrUnsupportedPlatform
+}
+
+// PlanSync is not supported on this platform and returns ErrUnsupportedPlatform
+func PlanSync(owner string, desired []StartupEntry, registryType StartupRegistryType) (SyncPlan, error) {
+	return SyncPlan{}, ErrUnsupportedPlatform
+}
+
+// ApplyPlan is not supported on this platform and returns ErrUnsupportedPlatform
+func ApplyPlan(plan SyncPlan, opts ...ApplyOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// ResolveRunOnceEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func ResolveRunOnceEntry(name string) (cleanName string, command string, deleteOnlyOnSuccess bool, safeMode bool, err error) {
+	return "", "", false, false, ErrUnsupportedPlatform
+}
+
+// FindCrossViewDuplicates is not supported on this platform and returns ErrUnsupportedPlatform
+func FindCrossViewDuplicates() ([]CrossViewDuplicate, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// HardenRunKeyACL is not supported on this platform and returns ErrUnsupportedPlatform
+func HardenRunKeyACL(registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// RestoreDefaultRunKeyACL is not supported on this platform and returns ErrUnsupportedPlatform
+func RestoreDefaultRunKeyACL(registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// ListAsTaskManager is not supported on this platform and returns ErrUnsupportedPlatform
+func ListAsTaskManager() ([]TaskManagerItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// AddStartupEntryPreferred is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupEntryPreferred(entry StartupEntry, preference []StartupRegistryType) (used StartupRegistryType, err error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+// ListRunOnceEx is not supported on this platform and returns ErrUnsupportedPlatform
+func ListRunOnceEx() ([]RunOnceExItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindByHashAllUsers is not supported on this platform and returns ErrUnsupportedPlatform
+func FindByHashAllUsers(sha256 string) (map[string][]StartupItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindReparsePointEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func FindReparsePointEntries() ([]StartupItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// AddStartupEntryEnabledAtomic is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupEntryEnabledAtomic(entry StartupEntry, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// EntriesGroupedByApproxInstallTime is not supported on this platform and returns ErrUnsupportedPlatform
+func EntriesGroupedByApproxInstallTime(window time.Duration) (map[time.Time][]StartupItem, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// CheckArchitectureCompatibility is not supported on this platform and returns ErrUnsupportedPlatform
+func CheckArchitectureCompatibility(entry StartupEntry) (compatible bool, exeArch string, osArch string, err error) {
+	return false, "", "", ErrUnsupportedPlatform
+}
+
+// PinEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func PinEntry(name string, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// UnpinEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func UnpinEntry(name string, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// ExpandForUser is not supported on this platform and returns ErrUnsupportedPlatform
+func ExpandForUser(command string, sid string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+// WatchStartupFolders is not supported on this platform and returns ErrUnsupportedPlatform
+func WatchStartupFolders(ctx context.Context) (<-chan FolderChangeEvent, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// Enabled is not supported on this platform and returns ErrUnsupportedPlatform
+func (t *Toggle) Enabled() (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// Enable is not supported on this platform and returns ErrUnsupportedPlatform
+func (t *Toggle) Enable() error {
+	return ErrUnsupportedPlatform
+}
+
+// Disable is not supported on this platform and returns ErrUnsupportedPlatform
+func (t *Toggle) Disable() error {
+	return ErrUnsupportedPlatform
+}
+
+// CopyStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func CopyStartupEntry(name string, from, to StartupRegistryType, newName string) error {
+	return ErrUnsupportedPlatform
+}
+
+// MoveStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func MoveStartupEntry(name string, from, to StartupRegistryType, newName string) error {
+	return ErrUnsupportedPlatform
+}
+
+// AddStartupShortcut is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupShortcut(entry StartupEntry) error {
+	return ErrUnsupportedPlatform
+}
+
+// RemoveStartupShortcut is not supported on this platform and returns ErrUnsupportedPlatform
+func RemoveStartupShortcut(name string) error {
+	return ErrUnsupportedPlatform
+}
+
+// ListStartupShortcuts is not supported on this platform and returns ErrUnsupportedPlatform
+func ListStartupShortcuts() (map[string]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ExportStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func ExportStartupEntries(w io.Writer) error {
+	return ErrUnsupportedPlatform
+}
+
+// ImportStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func ImportStartupEntries(r io.Reader, overwrite bool) (written int, err error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+// IsElevated is not supported on this platform and returns ErrUnsupportedPlatform
+func IsElevated() (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// RenameStartupEntry is not supported on this platform and returns ErrUnsupportedPlatform
+func RenameStartupEntry(oldName, newName string, registryType StartupRegistryType, opts ...RenameOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// WatchStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func WatchStartupEntries(ctx context.Context, registryType StartupRegistryType) (<-chan []StartupEntry, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// AddStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func AddStartupEntries(entries []StartupEntry, registryType StartupRegistryType, opts ...AddOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// RemoveStartupEntries is not supported on this platform and returns ErrUnsupportedPlatform
+func RemoveStartupEntries(names []string, registryType StartupRegistryType, opts ...RemoveOption) error {
+	return ErrUnsupportedPlatform
+}
+
+// ListStartupEntriesForAllUsers is not supported on this platform and returns ErrUnsupportedPlatform
+func ListStartupEntriesForAllUsers() (map[string]map[string]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// AccountNameForSID is not supported on this platform and returns ErrUnsupportedPlatform
+func AccountNameForSID(sid string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+// RegisterSelf is not supported on this platform and returns ErrUnsupportedPlatform
+func RegisterSelf(name string, registryType StartupRegistryType) error {
+	return ErrUnsupportedPlatform
+}
+
+// UnregisterSelf is not supported on this platform and returns ErrUnsupportedPlatform
+func UnregisterSelf(name string) error {
+	return ErrUnsupportedPlatform
+}
+
+// ListStartupEntriesDetailed is not supported on this platform and returns ErrUnsupportedPlatform
+func ListStartupEntriesDetailed(registryType StartupRegistryType) ([]StartupEntryInfo, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ListAllStartupEntriesDetailed is not supported on this platform and returns ErrUnsupportedPlatform
+func ListAllStartupEntriesDetailed() ([]StartupEntryInfo, error) {
+	return nil, ErrUnsupportedPlatform
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package winstartupreg_test
+
+import (
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/nishansanjuka/winstartupreg"
+)
+
+var _ = Describe("Unsupported Platform", func() {
+	It("Should report ErrUnsupportedPlatform instead of touching anything", func() {
+		entry := winstartupreg.StartupEntry{
+			Name:    "TestApp",
+			Command: "/usr/bin/true",
+		}
+
+		Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(MatchError(winstartupreg.ErrUnsupportedPlatform))
+		Expect(winstartupreg.RemoveStartupEntry(entry.Name, winstartupreg.CurrentUserRun)).To(MatchError(winstartupreg.ErrUnsupportedPlatform))
+		Expect(winstartupreg.SafeRemoveStartupEntry(entry.Name)).To(MatchError(winstartupreg.ErrUnsupportedPlatform))
+
+		_, err := winstartupreg.ListAllStartupEntries()
+		Expect(err).To(MatchError(winstartupreg.ErrUnsupportedPlatform))
+
+		Expect(winstartupreg.WriteReport(io.Discard)).To(MatchError(winstartupreg.ErrUnsupportedPlatform))
+	})
+})
@@ -0,0 +1,13 @@
+package winstartupreg_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWindowsStartupRegistry(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Windows Startup Registry Suite")
+}
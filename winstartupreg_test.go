@@ -1,23 +1,28 @@
+//go:build windows
+
 package winstartupreg_test
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
-	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 
 	"github.com/nishansanjuka/winstartupreg"
 )
 
-func TestWindowsStartupRegistry(t *testing.T) {
-	RegisterFailHandler(Fail)
-	RunSpecs(t, "Windows Startup Registry Suite")
-}
-
 var _ = Describe("Windows Startup Registry Management", func() {
 	var (
 		testAppName string
@@ -56,6 +61,41 @@ var _ = Describe("Windows Startup Registry Management", func() {
 				Expect(entries).To(HaveKey(testAppName))
 				Expect(entries[testAppName]).To(Equal(testCommand))
 			})
+
+			It("Should store arguments after the executable and read them back", func() {
+				entry := winstartupreg.StartupEntry{
+					Name:    testAppName,
+					Command: testCommand,
+					Args:    []string{"--minimized", "--profile", "Work Profile"},
+				}
+				Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+
+				entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+				Expect(err).To(BeNil())
+				Expect(entries[testAppName]).To(HaveSuffix(` --minimized --profile "Work Profile"`))
+				Expect(winstartupreg.ParseStartupEntry(testAppName, entries[testAppName])).To(Equal(entry))
+			})
+
+			It("Should quote an executable path that contains spaces", func() {
+				dir := filepath.Join(filepath.Dir(testCommand), "My App")
+				Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+				spaced := filepath.Join(dir, "app.exe")
+				Expect(os.WriteFile(spaced, []byte{0x4D, 0x5A}, 0o755)).To(Succeed())
+
+				entry := winstartupreg.StartupEntry{Name: testAppName, Command: spaced}
+				Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+
+				k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.QUERY_VALUE)
+				Expect(err).To(BeNil())
+				defer k.Close()
+				stored, _, err := k.GetStringValue(testAppName)
+				Expect(err).To(BeNil())
+				Expect(stored).To(Equal(`"` + spaced + `"`))
+
+				entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+				Expect(err).To(BeNil())
+				Expect(entries[testAppName]).To(Equal(spaced))
+			})
 		})
 
 		Context("With invalid input", func() {
@@ -68,6 +108,23 @@ var _ = Describe("Windows Startup Registry Management", func() {
 				err := winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)
 				Expect(err).To(HaveOccurred())
 			})
+
+			It("Should reject a directory as the executable", func() {
+				entry := winstartupreg.StartupEntry{Name: testAppName, Command: filepath.Dir(testCommand)}
+				err := winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)
+				Expect(err).To(MatchError(ContainSubstring("not an executable")))
+			})
+
+			It("Should reject a file without a PE header when validating images", func() {
+				entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+				err := winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun, winstartupreg.WithPEValidation())
+				Expect(err).To(MatchError(ContainSubstring("not an executable")))
+
+				self, err := os.Executable()
+				Expect(err).To(BeNil())
+				entry.Command = self
+				Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun, winstartupreg.WithPEValidation())).To(Succeed())
+			})
 		})
 	})
 
@@ -95,6 +152,14 @@ var _ = Describe("Windows Startup Registry Management", func() {
 				Expect(entries).ToNot(HaveKey("TestApp"))
 			}
 		})
+
+		It("Should report a missing entry with ErrEntryNotFound", func() {
+			err := winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrEntryNotFound))
+
+			err = winstartupreg.SafeRemoveStartupEntry(testAppName)
+			Expect(err).To(MatchError(winstartupreg.ErrEntryNotFound))
+		})
 	})
 
 	Describe("Listing Startup Entries", func() {
@@ -152,38 +217,2171 @@ var _ = Describe("Windows Startup Registry Management", func() {
 		})
 	})
 
-	Describe("Safe Remove Startup Entry", func() {
-		Context("When entry exists", func() {
-			BeforeEach(func() {
-				// Add an entry before each test
-				entry := winstartupreg.StartupEntry{
-					Name:    testAppName,
-					Command: testCommand,
+	Describe("Ensure Absent", func() {
+		It("Should remove an existing entry and report it was present", func() {
+			entry := winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			wasPresent, err := winstartupreg.EnsureAbsent(testAppName)
+			Expect(err).To(BeNil())
+			Expect(wasPresent).To(BeTrue())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+		})
+
+		It("Should be a no-op when the entry is already gone", func() {
+			wasPresent, err := winstartupreg.EnsureAbsent(testAppName)
+			Expect(err).To(BeNil())
+			Expect(wasPresent).To(BeFalse())
+		})
+	})
+
+	Describe("Listing Startup Entries By Value Type", func() {
+		BeforeEach(func() {
+			entry := winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}
+
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should include entries of the requested type", func() {
+			entries, err := winstartupreg.ListStartupEntriesByType(winstartupreg.CurrentUserRun, registry.SZ)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, testCommand))
+		})
+
+		It("Should exclude entries of other types", func() {
+			entries, err := winstartupreg.ListStartupEntriesByType(winstartupreg.CurrentUserRun, registry.EXPAND_SZ)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+		})
+	})
+
+	Describe("Ordering Numbered Startup Entries", func() {
+		const policyRunPath = `Software\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`
+		var otherCommand string
+
+		BeforeEach(func() {
+			var err error
+			otherCommand, err = createTempExecutable()
+			Expect(err).To(BeNil())
+
+			// The specs renumber the real policy key, put its values back afterwards
+			saved, err := winstartupreg.ListStartupItems(winstartupreg.CurrentUserPolicyRun)
+			Expect(err).To(BeNil())
+			k, err := registry.OpenKey(registry.CURRENT_USER, policyRunPath, registry.QUERY_VALUE)
+			existed := err == nil
+			if existed {
+				k.Close()
+			}
+
+			DeferCleanup(func() {
+				if !existed {
+					_ = registry.DeleteKey(registry.CURRENT_USER, policyRunPath)
+					return
 				}
 
-				_ = winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)
+				current, err := winstartupreg.ListStartupItems(winstartupreg.CurrentUserPolicyRun)
+				Expect(err).To(BeNil())
+				k, err := registry.OpenKey(registry.CURRENT_USER, policyRunPath, registry.SET_VALUE)
+				Expect(err).To(BeNil())
+				defer k.Close()
+				for _, item := range current {
+					Expect(k.DeleteValue(item.Name)).To(Succeed())
+				}
+				for _, item := range saved {
+					if item.ValueType == registry.EXPAND_SZ {
+						Expect(k.SetExpandStringValue(item.Name, item.Command)).To(Succeed())
+					} else {
+						Expect(k.SetStringValue(item.Name, item.Command)).To(Succeed())
+					}
+				}
 			})
 
-			It("Should safely remove the startup entry", func() {
-				err1 := winstartupreg.SafeRemoveStartupEntry(testAppName)
-				Expect(err1).To(BeNil())
-
-				// Verify the entry was removed
-				entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			// Start from an empty key so the ordinals below are predictable
+			if len(saved) > 0 {
+				k, err := registry.OpenKey(registry.CURRENT_USER, policyRunPath, registry.SET_VALUE)
 				Expect(err).To(BeNil())
-				Expect(entries).ToNot(HaveKey(testAppName))
+				defer k.Close()
+				for _, item := range saved {
+					Expect(k.DeleteValue(item.Name)).To(Succeed())
+				}
+			}
+		})
+
+		It("Should shift existing entries when inserting at an ordinal", func() {
+			Expect(winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: testCommand}, 1, winstartupreg.CurrentUserPolicyRun)).To(Succeed())
+			Expect(winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: otherCommand}, 1, winstartupreg.CurrentUserPolicyRun)).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserPolicyRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue("1", otherCommand))
+			Expect(entries).To(HaveKeyWithValue("2", testCommand))
+		})
+
+		It("Should renumber entries in the requested order", func() {
+			Expect(winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: testCommand}, 1, winstartupreg.CurrentUserPolicyRun)).To(Succeed())
+			Expect(winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: otherCommand}, 2, winstartupreg.CurrentUserPolicyRun)).To(Succeed())
+
+			Expect(winstartupreg.ReorderStartupEntries(winstartupreg.CurrentUserPolicyRun, []string{"2"})).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserPolicyRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue("1", otherCommand))
+			Expect(entries).To(HaveKeyWithValue("2", testCommand))
+		})
+
+		It("Should report policy entries as enforced", func() {
+			Expect(winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: testCommand}, 1, winstartupreg.CurrentUserPolicyRun)).To(Succeed())
+
+			items, err := winstartupreg.ListEnforcedStartupItems()
+			Expect(err).To(BeNil())
+			Expect(items).To(ContainElement(winstartupreg.StartupItem{
+				Name:      "1",
+				Command:   testCommand,
+				ValueType: registry.SZ,
+				Location:  winstartupreg.CurrentUserPolicyRun,
+				Enforced:  true,
+			}))
+		})
+
+		It("Should reject commands with NUL characters", func() {
+			err := winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: testCommand, Args: []string{"a\x00b"}}, 1, winstartupreg.CurrentUserPolicyRun)
+			Expect(err).To(MatchError(ContainSubstring("NUL")))
+
+			items, err := winstartupreg.ListStartupItems(winstartupreg.CurrentUserPolicyRun)
+			Expect(err).To(BeNil())
+			Expect(items).To(BeEmpty())
+		})
+
+		It("Should reject locations without numbered names", func() {
+			err := winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: testCommand}, 1, winstartupreg.CurrentUserRun)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Detecting System Entries", func() {
+		It("Should treat executables under the Windows directory as system", func() {
+			isSystem, err := winstartupreg.IsSystemEntry(winstartupreg.StartupEntry{
+				Name:    "Notepad",
+				Command: `%WINDIR%\System32\notepad.exe`,
 			})
+			Expect(err).To(BeNil())
+			Expect(isSystem).To(BeTrue())
 		})
 
-		Context("When entry does not exist", func() {
-			It("Should return an error", func() {
-				err := winstartupreg.SafeRemoveStartupEntry("TestApp")
-				Expect(err).To(HaveOccurred())
+		It("Should not treat executables in user-writable directories under the Windows directory as system", func() {
+			f, err := os.CreateTemp(filepath.Join(os.Getenv("WINDIR"), "Temp"), "winstartupreg-*.exe")
+			if err != nil {
+				Skip("cannot write to the Windows Temp directory: " + err.Error())
+			}
+			DeferCleanup(os.Remove, f.Name())
+			_, err = f.Write([]byte("MZ"))
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+
+			isSystem, err := winstartupreg.IsSystemEntry(winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: f.Name(),
+			})
+			Expect(err).To(BeNil())
+			Expect(isSystem).To(BeFalse())
+		})
+
+		It("Should not treat unsigned third-party executables as system", func() {
+			isSystem, err := winstartupreg.IsSystemEntry(winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
 			})
+			Expect(err).To(BeNil())
+			Expect(isSystem).To(BeFalse())
 		})
 	})
 
-})
+	Describe("Writing Reports", func() {
+		BeforeEach(func() {
+			entry := winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should write an aligned table of all entries", func() {
+			var report strings.Builder
+			Expect(winstartupreg.WriteReport(&report)).To(Succeed())
+			Expect(report.String()).To(HavePrefix("LOCATION"))
+			Expect(report.String()).To(ContainSubstring(testAppName))
+
+			fmt.Print(report.String())
+		})
+
+		It("Should include unsigned entries when only problems are requested", func() {
+			var report strings.Builder
+			Expect(winstartupreg.WriteReport(&report, winstartupreg.WithProblemsOnly())).To(Succeed())
+			Expect(report.String()).To(ContainSubstring(testAppName))
+		})
+
+		It("Should treat a present script as a healthy script launch", func() {
+			script := filepath.Join(filepath.Dir(testCommand), "start.bat")
+			Expect(os.WriteFile(script, []byte("@echo off\r\n"), 0o644)).To(Succeed())
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: script}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			diagnostics, err := winstartupreg.DiagnoseStartupEntries()
+			Expect(err).To(BeNil())
+			Expect(diagnostics).To(ContainElement(And(
+				HaveField("Name", testAppName),
+				HaveField("Script", script),
+				HaveField("Exists", true),
+				WithTransform(winstartupreg.StartupDiagnostic.Problem, BeFalse()),
+			)))
+		})
+
+		It("Should count a catalog-signed inbox binary as signed", func() {
+			// ctfmon.exe carries no embedded signature, a system catalog signs it
+			inbox := filepath.Join(os.Getenv("WINDIR"), "System32", "ctfmon.exe")
+			if _, err := os.Stat(inbox); err != nil {
+				Skip("ctfmon.exe is not available")
+			}
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: inbox}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			diagnostics, err := winstartupreg.DiagnoseStartupEntries()
+			Expect(err).To(BeNil())
+			Expect(diagnostics).To(ContainElement(And(
+				HaveField("Name", testAppName),
+				HaveField("Signed", true),
+				WithTransform(winstartupreg.StartupDiagnostic.Problem, BeFalse()),
+			)))
+		})
+
+		It("Should export the diagnostics as CSV", func() {
+			var export strings.Builder
+			Expect(winstartupreg.ExportCSV(&export)).To(Succeed())
+
+			records, err := csv.NewReader(strings.NewReader(export.String())).ReadAll()
+			Expect(err).To(BeNil())
+			Expect(records[0]).To(Equal([]string{"location", "name", "command", "executable", "enabled", "exists", "signed"}))
+			Expect(records).To(ContainElement(ContainElements(testAppName, testCommand, "true", "false")))
+		})
+	})
+
+	Describe("Quarantining Entries", func() {
+		var quarantinePath string
+
+		BeforeEach(func() {
+			quarantinePath = filepath.Join(GinkgoT().TempDir(), "quarantine.json")
+
+			entry := winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should remove quarantined entries and restore them from the file", func() {
+			Expect(winstartupreg.QuarantineToFile([]string{testAppName}, quarantinePath)).To(Succeed())
+			Expect(quarantinePath).To(BeARegularFile())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+
+			Expect(winstartupreg.RestoreFromQuarantineFile(quarantinePath)).To(Succeed())
+
+			entries, err = winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, testCommand))
+		})
+
+		It("Should restore the metadata of quarantined entries", func() {
+			DeferCleanup(removeTestMetadata, testAppName)
+			metadata := winstartupreg.EntryMetadata{Owner: "winstartupreg-tests", DisplayName: "Test App"}
+			Expect(winstartupreg.SetEntryMetadata(testAppName, winstartupreg.CurrentUserRun, metadata)).To(Succeed())
+
+			Expect(winstartupreg.QuarantineToFile([]string{testAppName}, quarantinePath)).To(Succeed())
+			_, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrNoMetadata))
+
+			Expect(winstartupreg.RestoreFromQuarantineFile(quarantinePath)).To(Succeed())
+			restored, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(restored.Owner).To(Equal(metadata.Owner))
+			Expect(restored.DisplayName).To(Equal(metadata.DisplayName))
+		})
+
+		It("Should not quarantine or remove an entry whose metadata cannot be read", func() {
+			DeferCleanup(removeTestMetadata, testAppName)
+			k, _, err := registry.CreateKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run\__meta\`+testAppName, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetStringValue("", "{not json")).To(Succeed())
+			k.Close()
+
+			Expect(winstartupreg.QuarantineToFile([]string{testAppName}, quarantinePath)).ToNot(Succeed())
+			Expect(quarantinePath).ToNot(BeAnExistingFile())
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).ToNot(Succeed())
+
+			exists, err := winstartupreg.EntryExists(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeTrue())
+
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.WithForce())).To(Succeed())
+		})
+
+		It("Should not remove anything when an entry cannot be captured", func() {
+			err := winstartupreg.QuarantineToFile([]string{testAppName, "MissingTestApp"}, quarantinePath)
+			Expect(err).To(HaveOccurred())
+			Expect(quarantinePath).ToNot(BeAnExistingFile())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKey(testAppName))
+		})
+	})
+
+	Describe("Finding Entries By Pattern", func() {
+		BeforeEach(func() {
+			entry := winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should match value names case-insensitively", func() {
+			entries, err := winstartupreg.FindEntries("testa*", winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, testCommand))
+		})
+
+		It("Should reject malformed patterns", func() {
+			_, err := winstartupreg.FindEntries("[", winstartupreg.CurrentUserRun)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Taking Snapshots", func() {
+		BeforeEach(func() {
+			entry := winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should capture entries as stored by default", func() {
+			snapshot, err := winstartupreg.TakeSnapshot()
+			Expect(err).To(BeNil())
+			Expect(snapshot.DriveLettersNormalized).To(BeFalse())
+			Expect(snapshot.Entries[winstartupreg.CurrentUserRun]).To(HaveKeyWithValue(testAppName, testCommand))
+		})
+
+		It("Should replace the system drive with a placeholder when normalizing", func() {
+			snapshot, err := winstartupreg.TakeSnapshot(winstartupreg.WithDriveLetterNormalization())
+			Expect(err).To(BeNil())
+			Expect(snapshot.DriveLettersNormalized).To(BeTrue())
+
+			if strings.EqualFold(filepath.VolumeName(testCommand), os.Getenv("SystemDrive")) {
+				expected := "<SYSTEMDRIVE>" + strings.TrimPrefix(testCommand, filepath.VolumeName(testCommand))
+				Expect(snapshot.Entries[winstartupreg.CurrentUserRun]).To(HaveKeyWithValue(testAppName, expected))
+			}
+		})
+	})
+
+	Describe("Detecting Elevation Requirements", func() {
+		It("Should report executables that run as the invoker", func() {
+			elevated, err := winstartupreg.RequiresElevation(winstartupreg.StartupEntry{
+				Name:    "Notepad",
+				Command: `%WINDIR%\System32\notepad.exe`,
+			})
+			Expect(err).To(BeNil())
+			Expect(elevated).To(BeFalse())
+		})
+
+		It("Should report executables whose manifest requests elevation", func() {
+			elevated, err := winstartupreg.RequiresElevation(winstartupreg.StartupEntry{
+				Name:    "Regedit",
+				Command: `%WINDIR%\regedit.exe`,
+			})
+			Expect(err).To(BeNil())
+			Expect(elevated).To(BeTrue())
+		})
+	})
+
+	Describe("Looking Up Single Entries", func() {
+		It("Should report a missing entry without an error", func() {
+			exists, err := winstartupreg.EntryExists(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeFalse())
+
+			_, found, err := winstartupreg.GetStartupEntry(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(found).To(BeFalse())
+		})
+
+		It("Should return the entry as it was added", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand, Args: []string{"--tray"}}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			exists, err := winstartupreg.EntryExists(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeTrue())
+
+			read, found, err := winstartupreg.GetStartupEntry(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(found).To(BeTrue())
+			Expect(read).To(Equal(entry))
+		})
+	})
+
+	Describe("Reading Canonical Commands", func() {
+		AfterEach(func() {
+			_ = winstartupreg.RemoveStartupEntry(testAppName+"_padded", winstartupreg.CurrentUserRun)
+		})
+
+		It("Should expose padding that the canonical form removes", func() {
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetStringValue(testAppName+"_padded", "  "+testCommand+"   --flag\t ")).To(Succeed())
+			k.Close()
+
+			raw, canonical, err := winstartupreg.GetStartupEntryCanonical(testAppName+"_padded", winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(raw).To(Equal("  " + testCommand + "   --flag\t "))
+			Expect(canonical).To(Equal(testCommand + " --flag"))
+		})
+	})
+
+	Describe("Listing Effective Startup Entries", func() {
+		AfterEach(func() {
+			_ = winstartupreg.RemoveStartupEntry(testAppName+"_missing", winstartupreg.CurrentUserRun)
+		})
+
+		It("Should tell entries that will run from ones with a missing executable", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetStringValue(testAppName+"_missing", testCommand+".gone")).To(Succeed())
+			k.Close()
+
+			entries, err := winstartupreg.ListEffectiveStartupEntries()
+			Expect(err).To(BeNil())
+
+			states := make(map[string]string)
+			for _, entry := range entries {
+				if entry.Location == winstartupreg.CurrentUserRun {
+					states[entry.Name] = entry.Reason
+				}
+			}
+			Expect(states).To(HaveKeyWithValue(testAppName, ""))
+			Expect(states).To(HaveKeyWithValue(testAppName+"_missing", "executable not found"))
+		})
+	})
+
+	Describe("Compare And Swap", func() {
+		It("Should create an absent entry when the expected command is empty", func() {
+			swapped, err := winstartupreg.CompareAndSwap(testAppName, "", testCommand, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(swapped).To(BeTrue())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, testCommand))
+		})
+
+		It("Should only swap when the current command matches", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			swapped, err := winstartupreg.CompareAndSwap(testAppName, "stale", testCommand+" --new", winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(swapped).To(BeFalse())
+
+			swapped, err = winstartupreg.CompareAndSwap(testAppName, testCommand, testCommand+" --new", winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(swapped).To(BeTrue())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, testCommand+" --new"))
+		})
+	})
+
+	Describe("Entry Metadata", func() {
+		AfterEach(func() {
+			removeTestMetadata(testAppName)
+		})
+
+		It("Should report entries without metadata", func() {
+			_, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrNoMetadata))
+		})
+
+		It("Should round-trip typed metadata", func() {
+			createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			Expect(winstartupreg.SetEntryMetadata(testAppName, winstartupreg.CurrentUserRun, winstartupreg.EntryMetadata{
+				Owner:       "test-suite",
+				DisplayName: "Test App",
+				CreatedAt:   createdAt,
+			})).To(Succeed())
+
+			metadata, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(metadata.SchemaVersion).To(Equal(winstartupreg.MetadataSchemaVersion))
+			Expect(metadata.Owner).To(Equal("test-suite"))
+			Expect(metadata.DisplayName).To(Equal("Test App"))
+			Expect(metadata.CreatedAt).To(BeTemporally("==", createdAt))
+		})
+	})
+
+	Describe("Self Repair", func() {
+		var installRoot, movedCommand string
+
+		BeforeEach(func() {
+			installRoot = GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(installRoot, "app-2.0"), 0o755)).To(Succeed())
+			movedCommand = filepath.Join(installRoot, "app-2.0", "testapp.exe")
+			Expect(os.WriteFile(movedCommand, []byte{0x4D, 0x5A}, 0o644)).To(Succeed())
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetStringValue(testAppName, filepath.Join(installRoot, "app-1.0", "testapp.exe")+" --tray")).To(Succeed())
+			k.Close()
+
+			Expect(winstartupreg.SetEntryMetadata(testAppName, winstartupreg.CurrentUserRun, winstartupreg.EntryMetadata{Owner: "test-suite"})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			removeTestMetadata(testAppName)
+		})
+
+		It("Should only report changes in dry-run mode", func() {
+			changes, err := winstartupreg.SelfRepair("test-suite", winstartupreg.WithInstallRoot(installRoot), winstartupreg.WithDryRun())
+			Expect(err).To(BeNil())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Applied).To(BeFalse())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries[testAppName]).To(Equal(changes[0].OldCommand))
+		})
+
+		It("Should point owned entries at the moved executable", func() {
+			changes, err := winstartupreg.SelfRepair("test-suite", winstartupreg.WithInstallRoot(installRoot))
+			Expect(err).To(BeNil())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Applied).To(BeTrue())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, movedCommand+" --tray"))
+		})
+
+		It("Should quote an unquoted executable path with spaces", func() {
+			spaced := filepath.Join(installRoot, "Program Files", "testapp.exe")
+			Expect(os.MkdirAll(filepath.Dir(spaced), 0o755)).To(Succeed())
+			Expect(os.WriteFile(spaced, []byte{0x4D, 0x5A}, 0o644)).To(Succeed())
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetStringValue(testAppName, spaced+" --tray")).To(Succeed())
+			k.Close()
+
+			changes, err := winstartupreg.SelfRepair("test-suite", winstartupreg.WithInstallRoot(installRoot))
+			Expect(err).To(BeNil())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Reason).To(Equal("unquoted path with spaces"))
+			Expect(changes[0].Applied).To(BeTrue())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, `"`+spaced+`" --tray`))
+		})
+
+		It("Should leave entries of other owners alone", func() {
+			changes, err := winstartupreg.SelfRepair("someone-else", winstartupreg.WithInstallRoot(installRoot))
+			Expect(err).To(BeNil())
+			Expect(changes).To(BeEmpty())
+		})
+	})
+
+	Describe("Touching the Own Entry", func() {
+		It("Should point the entry at the running executable and keep its arguments", func() {
+			self, err := os.Executable()
+			Expect(err).To(BeNil())
+			if resolved, err := filepath.EvalSymlinks(self); err == nil {
+				self = resolved
+			}
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetStringValue(testAppName, `C:\old-version\testapp.exe --tray`)).To(Succeed())
+			k.Close()
+
+			updated, err := winstartupreg.TouchSelfEntry(testAppName)
+			Expect(err).To(BeNil())
+			Expect(updated).To(BeTrue())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries[testAppName]).To(HaveSuffix(" --tray"))
+			Expect(winstartupreg.CommandExecutable(entries[testAppName])).To(Equal(self))
+
+			updated, err = winstartupreg.TouchSelfEntry(testAppName)
+			Expect(err).To(BeNil())
+			Expect(updated).To(BeFalse())
+		})
+
+		It("Should report no update for an absent entry", func() {
+			updated, err := winstartupreg.TouchSelfEntry("NonExistentApp")
+			Expect(err).To(BeNil())
+			Expect(updated).To(BeFalse())
+		})
+	})
+
+	Describe("Summarizing Startup Entries", func() {
+		It("Should return value names for every location", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			summary, err := winstartupreg.SummarizeStartupEntries()
+			Expect(err).To(BeNil())
+			Expect(summary).To(HaveKey(winstartupreg.CurrentUserRunOnce))
+			Expect(summary[winstartupreg.CurrentUserRun]).To(ContainElement(testAppName))
+		})
+	})
+
+	Describe("Adding No-Window Startup Entries", func() {
+		AfterEach(func() {
+			removeTestMetadata(testAppName)
+		})
+
+		It("Should register wscript.exe with a generated launcher and clean it up on removal", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddNoWindowStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			metadata, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(metadata.Launcher).To(HaveSuffix(testAppName + ".vbs"))
+
+			script, err := os.ReadFile(metadata.Launcher)
+			Expect(err).To(BeNil())
+			Expect(string(script)).To(ContainSubstring(testCommand))
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(strings.ToLower(entries[testAppName])).To(ContainSubstring("wscript.exe"))
+
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(metadata.Launcher).ToNot(BeAnExistingFile())
+		})
+
+		It("Should keep the launcher through a quarantine and restore", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddNoWindowStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+			metadata, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+
+			quarantinePath := filepath.Join(GinkgoT().TempDir(), "quarantine.json")
+			Expect(winstartupreg.QuarantineToFile([]string{testAppName}, quarantinePath)).To(Succeed())
+			Expect(metadata.Launcher).To(BeAnExistingFile())
+
+			Expect(winstartupreg.RestoreFromQuarantineFile(quarantinePath)).To(Succeed())
+			restored, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(restored.Launcher).To(Equal(metadata.Launcher))
+
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(metadata.Launcher).ToNot(BeAnExistingFile())
+		})
+
+		It("Should keep a launcher shared with a copy until both are removed", func() {
+			copyName := testAppName + "Copy"
+			DeferCleanup(func() {
+				_ = winstartupreg.RemoveStartupEntry(copyName, winstartupreg.CurrentUserRunOnce, winstartupreg.WithForce())
+				_ = registry.DeleteKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\RunOnce\__meta\`+copyName)
+			})
+
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddNoWindowStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+			metadata, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+
+			Expect(winstartupreg.CopyStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.CurrentUserRunOnce, copyName)).To(Succeed())
+
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(metadata.Launcher).To(BeAnExistingFile())
+
+			Expect(winstartupreg.RemoveStartupEntry(copyName, winstartupreg.CurrentUserRunOnce)).To(Succeed())
+			Expect(metadata.Launcher).ToNot(BeAnExistingFile())
+		})
+	})
+
+	Describe("Diffing a .reg File", func() {
+		It("Should compare expand strings by their unexpanded text and type", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			// "%SystemRoot%" as a NUL-terminated UTF-16LE expand string
+			regFile := "Windows Registry Editor Version 5.00\r\n\r\n" +
+				`[HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Run]` + "\r\n" +
+				`"` + testAppName + `"=-` + "\r\n" +
+				`"` + testAppName + `Expand"=hex(2):25,00,53,00,79,00,73,00,74,00,65,00,6d,00,52,00,\` + "\r\n" +
+				`  6f,00,6f,00,74,00,25,00,00,00` + "\r\n"
+
+			diff, err := winstartupreg.DiffRegFile(strings.NewReader(regFile))
+			Expect(err).To(BeNil())
+			Expect(diff.Removed).To(ContainElement(HaveField("Name", testAppName)))
+			Expect(diff.Added).To(ContainElement(winstartupreg.EntryDiff{
+				Location:     winstartupreg.CurrentUserRun,
+				Name:         testAppName + "Expand",
+				NewCommand:   "%SystemRoot%",
+				NewValueType: registry.EXPAND_SZ,
+			}))
+
+			// Nothing is applied
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKey(testAppName))
+			Expect(entries).ToNot(HaveKey(testAppName + "Expand"))
+		})
+	})
+
+	Describe("Disabling and Enabling Startup Entries", func() {
+		approvalKeys := map[winstartupreg.StartupRegistryType]registry.Key{
+			winstartupreg.CurrentUserRun: registry.CURRENT_USER,
+			winstartupreg.AllUsersRun:    registry.LOCAL_MACHINE,
+		}
+		const approvalPath = `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`
+
+		readBlob := func(registryType winstartupreg.StartupRegistryType) []byte {
+			k, err := registry.OpenKey(approvalKeys[registryType], approvalPath, registry.QUERY_VALUE)
+			Expect(err).To(BeNil())
+			defer k.Close()
+			blob, _, err := k.GetBinaryValue(testAppName)
+			Expect(err).To(BeNil())
+			return blob
+		}
+
+		DescribeTable("Should flip only the state bit of an existing blob",
+			func(registryType winstartupreg.StartupRegistryType, blob []byte) {
+				entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+				if err := winstartupreg.AddStartupEntry(entry, registryType); err != nil {
+					Skip("cannot write " + registryType.String() + ": " + err.Error())
+				}
+				DeferCleanup(func() {
+					_ = winstartupreg.RemoveStartupEntry(testAppName, registryType)
+					if k, err := registry.OpenKey(approvalKeys[registryType], approvalPath, registry.SET_VALUE); err == nil {
+						_ = k.DeleteValue(testAppName)
+						k.Close()
+					}
+				})
+
+				k, _, err := registry.CreateKey(approvalKeys[registryType], approvalPath, registry.SET_VALUE)
+				Expect(err).To(BeNil())
+				Expect(k.SetBinaryValue(testAppName, blob)).To(Succeed())
+				k.Close()
+
+				Expect(winstartupreg.DisableStartupEntry(testAppName, registryType)).To(Succeed())
+				disabled := readBlob(registryType)
+				Expect(disabled[0]).To(Equal(blob[0] | 0x01))
+				Expect(disabled[1:]).To(Equal(blob[1:]))
+
+				Expect(winstartupreg.EnableStartupEntry(testAppName, registryType)).To(Succeed())
+				Expect(readBlob(registryType)).To(Equal(blob))
+			},
+			Entry("HKCU with a 12 byte blob", winstartupreg.CurrentUserRun, []byte{0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+			Entry("HKCU with a longer blob", winstartupreg.CurrentUserRun, []byte{0x06, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}),
+			Entry("HKLM with a 12 byte blob", winstartupreg.AllUsersRun, []byte{0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+			Entry("HKLM with a longer blob", winstartupreg.AllUsersRun, []byte{0x06, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}),
+		)
+
+		It("Should create a minimal blob when there is none", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			enabled, err := winstartupreg.IsStartupEntryEnabled(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeTrue())
+
+			Expect(winstartupreg.DisableStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			blob := readBlob(winstartupreg.CurrentUserRun)
+			Expect(blob).To(HaveLen(12))
+			Expect(blob[0]).To(Equal(byte(0x03)))
+			enabled, err = winstartupreg.IsStartupEntryEnabled(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeFalse())
+
+			Expect(winstartupreg.EnableStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(readBlob(winstartupreg.CurrentUserRun)[0]).To(Equal(byte(0x02)))
+			enabled, err = winstartupreg.IsStartupEntryEnabled(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeTrue())
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, approvalPath, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			_ = k.DeleteValue(testAppName)
+			k.Close()
+		})
+
+		It("Should reject locations without approval state", func() {
+			Expect(winstartupreg.DisableStartupEntry(testAppName, winstartupreg.CurrentUserRunOnce)).ToNot(Succeed())
+			_, err := winstartupreg.IsStartupEntryEnabled(testAppName, winstartupreg.CurrentUserRunOnce)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should add an entry explicitly enabled over a stale disabled blob", func() {
+			k, _, err := registry.CreateKey(registry.CURRENT_USER, approvalPath, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetBinaryValue(testAppName, []byte{0x03, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8})).To(Succeed())
+			k.Close()
+			DeferCleanup(func() {
+				if k, err := registry.OpenKey(registry.CURRENT_USER, approvalPath, registry.SET_VALUE); err == nil {
+					_ = k.DeleteValue(testAppName)
+					k.Close()
+				}
+			})
+
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddStartupEntryEnabledAtomic(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(readBlob(winstartupreg.CurrentUserRun)[0]).To(Equal(byte(0x02)))
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKey(testAppName))
+		})
+
+		It("Should refuse the atomic add for locations without approval state", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddStartupEntryEnabledAtomic(entry, winstartupreg.CurrentUserRunOnce)).ToNot(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRunOnce)
+			if err == nil {
+				Expect(entries).ToNot(HaveKey(testAppName))
+			}
+		})
+	})
+
+	Describe("Listing Everything", func() {
+		It("Should include registry and Startup folder items in sorted order", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			items, err := winstartupreg.ListEverything()
+			Expect(err).To(BeNil())
+			Expect(items).To(ContainElement(And(
+				HaveField("Name", testAppName),
+				HaveField("Source", winstartupreg.SourceRegistry),
+				HaveField("Location", winstartupreg.CurrentUserRun),
+			)))
+
+			for i := 1; i < len(items); i++ {
+				Expect(items[i-1].Source).To(BeNumerically("<=", items[i].Source))
+			}
+		})
+
+		It("Should merge an entry found in both HKLM views into one item", func() {
+			var wow64 bool
+			Expect(windows.IsWow64Process(windows.CurrentProcess(), &wow64)).To(Succeed())
+			if runtime.GOARCH == "386" && !wow64 {
+				Skip("32-bit Windows has a single view")
+			}
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			for _, view := range []winstartupreg.RegistryView{winstartupreg.View64, winstartupreg.View32} {
+				if err := winstartupreg.AddStartupEntryWithView(entry, winstartupreg.AllUsersRun, view); err != nil {
+					Skip("cannot write AllUsersRun: " + err.Error())
+				}
+				DeferCleanup(winstartupreg.RemoveStartupEntryWithView, testAppName, winstartupreg.AllUsersRun, view)
+			}
+
+			items, err := winstartupreg.ListEverything()
+			Expect(err).To(BeNil())
+
+			var ours []winstartupreg.StartupItem
+			for _, item := range items {
+				if item.Name == testAppName && item.Location == winstartupreg.AllUsersRun {
+					ours = append(ours, item)
+				}
+			}
+			Expect(ours).To(HaveLen(1))
+			Expect(ours[0].Views).To(Equal([]winstartupreg.RegistryView{winstartupreg.View64, winstartupreg.View32}))
+		})
+	})
+
+	Describe("Adding Startup Entries With Auto Expand Type", func() {
+		It("Should store commands that reference a variable as REG_EXPAND_SZ", func() {
+			GinkgoT().Setenv("WINSTARTUPREG_TEST_DIR", filepath.Dir(testCommand))
+			command := `%WINSTARTUPREG_TEST_DIR%\` + filepath.Base(testCommand)
+
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: command}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun, winstartupreg.WithAutoExpandType())).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntriesByType(winstartupreg.CurrentUserRun, registry.EXPAND_SZ)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, command))
+		})
+
+		It("Should keep a literal % in a path as REG_SZ", func() {
+			dir := filepath.Join(filepath.Dir(testCommand), "100%")
+			Expect(os.Mkdir(dir, 0o755)).To(Succeed())
+			command := filepath.Join(dir, "testapp.exe")
+			Expect(os.WriteFile(command, nil, 0o755)).To(Succeed())
+
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: command}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun, winstartupreg.WithAutoExpandType())).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntriesByType(winstartupreg.CurrentUserRun, registry.SZ)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, command))
+		})
+
+		It("Should store any command as REG_EXPAND_SZ with WithExpandType", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun, winstartupreg.WithExpandType())).To(Succeed())
+
+			items, err := winstartupreg.ListStartupItems(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(items).To(ContainElement(And(
+				HaveField("Name", testAppName),
+				HaveField("Command", testCommand),
+				HaveField("ValueType", uint32(registry.EXPAND_SZ)),
+			)))
+		})
+	})
+
+	Describe("Checking Drift Against a Baseline", func() {
+		It("Should report no drift until an entry changes", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "baseline.json")
+			Expect(winstartupreg.SaveBaseline(path)).To(Succeed())
+
+			diff, err := winstartupreg.CheckDrift(path)
+			Expect(err).To(BeNil())
+			Expect(diff.Empty()).To(BeTrue())
+
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			diff, err = winstartupreg.CheckDrift(path)
+			Expect(err).To(BeNil())
+			Expect(diff.Added).To(ContainElement(HaveField("Name", testAppName)))
+		})
+	})
+
+	Describe("Repairing Malformed Commands", func() {
+		It("Should collapse doubled quotes and trim trailing garbage while keeping arguments", func() {
+			malformed := `""` + testCommand + `"" --tray;  `
+			swapped, err := winstartupreg.CompareAndSwap(testAppName, "", malformed, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(swapped).To(BeTrue())
+
+			expected := winstartupreg.Change{
+				Location:   winstartupreg.CurrentUserRun,
+				Name:       testAppName,
+				OldCommand: malformed,
+				NewCommand: `"` + testCommand + `" --tray`,
+				Reason:     "trailing garbage, doubled quotes",
+			}
+
+			changes, err := winstartupreg.RepairMalformedCommands(true)
+			Expect(err).To(BeNil())
+			Expect(changes).To(ContainElement(expected))
+
+			changes, err = winstartupreg.RepairMalformedCommands(false)
+			Expect(err).To(BeNil())
+			expected.Applied = true
+			Expect(changes).To(ContainElement(expected))
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries[testAppName]).To(Equal(expected.NewCommand))
+		})
+	})
+
+	Describe("Listing All Startup Entries With a Timeout", func() {
+		It("Should return the locations that answered in time", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			entries, locationErrs, err := winstartupreg.ListAllStartupEntriesTimeout(10 * time.Second)
+			Expect(err).To(BeNil())
+			Expect(locationErrs).To(BeEmpty())
+			Expect(entries[winstartupreg.CurrentUserRun]).To(HaveKey(testAppName))
+		})
+
+		It("Should reject a non-positive timeout", func() {
+			_, _, err := winstartupreg.ListAllStartupEntriesTimeout(0)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Syncing Owned Entries", func() {
+		AfterEach(func() {
+			removeTestMetadata(testAppName)
+			_ = winstartupreg.RemoveStartupEntry(testAppName+"Other", winstartupreg.CurrentUserRun)
+		})
+
+		It("Should add and later remove owned entries", func() {
+			desired := []winstartupreg.StartupEntry{{Name: testAppName, Command: testCommand}}
+			plan, err := winstartupreg.PlanSync("sync-test", desired, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(plan.Steps).To(ConsistOf(HaveField("Action", winstartupreg.SyncAdd)))
+			Expect(winstartupreg.ApplyPlan(plan, winstartupreg.WithStaleCheck())).To(Succeed())
+
+			metadata, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(metadata.Owner).To(Equal("sync-test"))
+
+			plan, err = winstartupreg.PlanSync("sync-test", nil, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(plan.Steps).To(ConsistOf(HaveField("Action", winstartupreg.SyncRemove)))
+			Expect(winstartupreg.ApplyPlan(plan)).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+		})
+
+		It("Should refuse a plan made stale by another writer", func() {
+			desired := []winstartupreg.StartupEntry{{Name: testAppName, Command: testCommand}}
+			plan, err := winstartupreg.PlanSync("sync-test", desired, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+
+			// Make sure the key's last-write time moves past the one captured by the plan
+			time.Sleep(20 * time.Millisecond)
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName + "Other", Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			Expect(winstartupreg.ApplyPlan(plan, winstartupreg.WithStaleCheck())).To(MatchError(winstartupreg.ErrStalePlan))
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+		})
+	})
+
+	Describe("Read-Only Mode", func() {
+		BeforeEach(func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			winstartupreg.SetReadOnly(true)
+			DeferCleanup(winstartupreg.SetReadOnly, false)
+		})
+
+		It("Should refuse every modification without touching the registry", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName + "New", Command: testCommand}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.AddNoWindowStartupEntry(entry, winstartupreg.CurrentUserRun)).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.AddStartupEntryAt(entry, 1, winstartupreg.CurrentUserPolicyRun)).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.SafeRemoveStartupEntry(testAppName)).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.DisableStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.SetEntryMetadata(testAppName, winstartupreg.CurrentUserRun, winstartupreg.EntryMetadata{Owner: "ro"})).To(MatchError(winstartupreg.ErrReadOnlyMode))
+
+			_, err := winstartupreg.CompareAndSwap(testAppName, testCommand, testCommand+" --changed", winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			_, err = winstartupreg.EnsureAbsent(testAppName)
+			Expect(err).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			_, err = winstartupreg.RepairMalformedCommands(false)
+			Expect(err).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.ApplyPlan(winstartupreg.SyncPlan{})).To(MatchError(winstartupreg.ErrReadOnlyMode))
+			Expect(winstartupreg.QuarantineToFile([]string{testAppName}, filepath.Join(GinkgoT().TempDir(), "q.json"))).To(MatchError(winstartupreg.ErrReadOnlyMode))
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(testAppName, testCommand))
+			Expect(entries).ToNot(HaveKey(entry.Name))
+		})
+
+		It("Should still allow dry runs", func() {
+			_, err := winstartupreg.RepairMalformedCommands(true)
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("Resolving RunOnce Entries", func() {
+		It("Should strip and interpret combined prefixes", func() {
+			stored := "!*" + testAppName
+			swapped, err := winstartupreg.CompareAndSwap(stored, "", testCommand, winstartupreg.CurrentUserRunOnce)
+			Expect(err).To(BeNil())
+			Expect(swapped).To(BeTrue())
+			DeferCleanup(winstartupreg.RemoveStartupEntry, stored, winstartupreg.CurrentUserRunOnce)
+
+			for _, name := range []string{stored, testAppName} {
+				cleanName, command, deleteOnlyOnSuccess, safeMode, err := winstartupreg.ResolveRunOnceEntry(name)
+				Expect(err).To(BeNil())
+				Expect(cleanName).To(Equal(testAppName))
+				Expect(command).To(Equal(testCommand))
+				Expect(deleteOnlyOnSuccess).To(BeTrue())
+				Expect(safeMode).To(BeTrue())
+			}
+		})
+	})
+
+	Describe("Finding Cross-View Duplicates", func() {
+		It("Should report names present in both HKLM views", func() {
+			const runPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`
+			for view, command := range map[uint32]string{registry.WOW64_64KEY: testCommand + " --64", registry.WOW64_32KEY: testCommand + " --32"} {
+				k, err := registry.OpenKey(registry.LOCAL_MACHINE, runPath, registry.SET_VALUE|view)
+				if err != nil {
+					Skip("cannot write HKLM: " + err.Error())
+				}
+				Expect(k.SetStringValue(testAppName, command)).To(Succeed())
+				k.Close()
+
+				DeferCleanup(func(view uint32) {
+					if k, err := registry.OpenKey(registry.LOCAL_MACHINE, runPath, registry.SET_VALUE|view); err == nil {
+						_ = k.DeleteValue(testAppName)
+						k.Close()
+					}
+				}, view)
+			}
+
+			duplicates, err := winstartupreg.FindCrossViewDuplicates()
+			Expect(err).To(BeNil())
+			Expect(duplicates).To(ContainElement(winstartupreg.CrossViewDuplicate{
+				Location:  winstartupreg.AllUsersRun,
+				Name:      testAppName,
+				Command64: testCommand + " --64",
+				Command32: testCommand + " --32",
+			}))
+		})
+	})
+
+	Describe("Hardening the Run Key ACL", func() {
+		It("Should protect the DACL and restore inheritance afterwards", func() {
+			if !windows.GetCurrentProcessToken().IsElevated() {
+				Skip("hardening requires an elevated process to undo")
+			}
+			const runPath = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+			control := func() windows.SECURITY_DESCRIPTOR_CONTROL {
+				k, err := registry.OpenKey(registry.CURRENT_USER, runPath, windows.READ_CONTROL)
+				Expect(err).To(BeNil())
+				defer k.Close()
+				sd, err := windows.GetSecurityInfo(windows.Handle(k), windows.SE_REGISTRY_KEY, windows.DACL_SECURITY_INFORMATION)
+				Expect(err).To(BeNil())
+				c, _, err := sd.Control()
+				Expect(err).To(BeNil())
+				return c
+			}
+
+			Expect(winstartupreg.HardenRunKeyACL(winstartupreg.CurrentUserRun)).To(Succeed())
+			DeferCleanup(winstartupreg.RestoreDefaultRunKeyACL, winstartupreg.CurrentUserRun)
+			Expect(control() & windows.SE_DACL_PROTECTED).ToNot(BeZero())
+
+			Expect(winstartupreg.RestoreDefaultRunKeyACL(winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(control() & windows.SE_DACL_PROTECTED).To(BeZero())
+		})
+	})
+
+	Describe("Listing as Task Manager", func() {
+		It("Should show Run entries with their state but not RunOnce entries", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName + "Once", Command: testCommand}, winstartupreg.CurrentUserRunOnce)).To(Succeed())
+			DeferCleanup(winstartupreg.RemoveStartupEntry, testAppName+"Once", winstartupreg.CurrentUserRunOnce)
+
+			items, err := winstartupreg.ListAsTaskManager()
+			Expect(err).To(BeNil())
+			Expect(items).To(ContainElement(And(
+				HaveField("Name", testAppName),
+				HaveField("DisplayName", testAppName),
+				HaveField("Enabled", true),
+				HaveField("Impact", winstartupreg.ImpactNotMeasured),
+			)))
+			Expect(items).ToNot(ContainElement(HaveField("Name", testAppName+"Once")))
+		})
+	})
+
+	Describe("Adding Startup Entries to a Preferred Location", func() {
+		It("Should fall back to the current user when all-users is denied", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			used, err := winstartupreg.AddStartupEntryPreferred(entry, []winstartupreg.StartupRegistryType{winstartupreg.AllUsersRun, winstartupreg.CurrentUserRun})
+			Expect(err).To(BeNil())
+			DeferCleanup(winstartupreg.RemoveStartupEntry, testAppName, winstartupreg.AllUsersRun)
+
+			if windows.GetCurrentProcessToken().IsElevated() {
+				Expect(used).To(Equal(winstartupreg.AllUsersRun))
+			} else {
+				Expect(used).To(Equal(winstartupreg.CurrentUserRun))
+			}
+		})
+
+		It("Should stop at a failure other than access denied", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: `C:\NonExistent\app.exe`}
+			_, err := winstartupreg.AddStartupEntryPreferred(entry, []winstartupreg.StartupRegistryType{winstartupreg.CurrentUserRun, winstartupreg.CurrentUserRunOnce})
+			Expect(err).To(MatchError(ContainSubstring("CurrentUserRun:")))
+			Expect(err).ToNot(MatchError(ContainSubstring("CurrentUserRunOnce:")))
+		})
+	})
+
+	Describe("Listing RunOnceEx", func() {
+		It("Should return sections and values in execution order", func() {
+			const runOnceExPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnceEx`
+			for section, values := range map[string]map[string]string{
+				"WinstartupregTestB": {"": "Second", "1": `||cmd.exe /c exit`},
+				"winstartupregTestA": {"2": `shell32.dll|Control_RunDLL|x`, "10": `||cmd.exe /c exit 10`},
+			} {
+				k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, runOnceExPath+`\`+section, registry.SET_VALUE)
+				if err != nil {
+					Skip("cannot write HKLM: " + err.Error())
+				}
+				DeferCleanup(registry.DeleteKey, registry.LOCAL_MACHINE, runOnceExPath+`\`+section)
+				for name, value := range values {
+					Expect(k.SetStringValue(name, value)).To(Succeed())
+				}
+				k.Close()
+			}
+
+			items, err := winstartupreg.ListRunOnceEx()
+			Expect(err).To(BeNil())
+
+			var ours []winstartupreg.RunOnceExItem
+			for _, item := range items {
+				if strings.HasPrefix(strings.ToLower(item.Section), "winstartupregtest") {
+					ours = append(ours, item)
+				}
+			}
+			Expect(ours).To(HaveLen(3))
+			Expect(ours[0]).To(And(HaveField("Section", "winstartupregTestA"), HaveField("Name", "10"), HaveField("Arguments", "cmd.exe /c exit 10")))
+			Expect(ours[1]).To(And(HaveField("Name", "2"), HaveField("DLL", "shell32.dll"), HaveField("Function", "Control_RunDLL")))
+			Expect(ours[2]).To(And(HaveField("Section", "WinstartupregTestB"), HaveField("SectionTitle", "Second")))
+		})
+
+		It("Should run the sections of keys named by Depend first", func() {
+			const parentPath = `SOFTWARE\Microsoft\Windows\CurrentVersion`
+			const dependPath = parentPath + `\RunOnceEx\Depend`
+			const dependencyPath = parentPath + `\WinstartupregTestRunOnceEx`
+
+			k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, dependencyPath+`\Section`, registry.SET_VALUE)
+			if err != nil {
+				Skip("cannot write HKLM: " + err.Error())
+			}
+			DeferCleanup(func() {
+				_ = registry.DeleteKey(registry.LOCAL_MACHINE, dependencyPath+`\Section`)
+				_ = registry.DeleteKey(registry.LOCAL_MACHINE, dependencyPath)
+			})
+			Expect(k.SetStringValue("1", `||cmd.exe /c exit 1`)).To(Succeed())
+			k.Close()
+
+			section, _, err := registry.CreateKey(registry.LOCAL_MACHINE, parentPath+`\RunOnceEx\WinstartupregTestMain`, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			DeferCleanup(registry.DeleteKey, registry.LOCAL_MACHINE, parentPath+`\RunOnceEx\WinstartupregTestMain`)
+			Expect(section.SetStringValue("1", `||cmd.exe /c exit 2`)).To(Succeed())
+			section.Close()
+
+			depend, created, err := registry.CreateKey(registry.LOCAL_MACHINE, dependPath, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			DeferCleanup(func() {
+				_ = depend.DeleteValue("WinstartupregTest")
+				depend.Close()
+				if created {
+					_ = registry.DeleteKey(registry.LOCAL_MACHINE, dependPath)
+				}
+			})
+			Expect(depend.SetStringValue("WinstartupregTest", "WinstartupregTestRunOnceEx")).To(Succeed())
+
+			items, err := winstartupreg.ListRunOnceEx()
+			Expect(err).To(BeNil())
+
+			var ours []winstartupreg.RunOnceExItem
+			for _, item := range items {
+				if strings.HasPrefix(strings.ToLower(item.Key), strings.ToLower(dependencyPath)) || item.Section == "WinstartupregTestMain" {
+					ours = append(ours, item)
+				}
+			}
+			Expect(ours).To(HaveLen(2))
+			Expect(ours[0]).To(And(HaveField("Key", dependencyPath), HaveField("Arguments", "cmd.exe /c exit 1")))
+			Expect(ours[1]).To(And(HaveField("Section", "WinstartupregTestMain"), HaveField("Arguments", "cmd.exe /c exit 2")))
+		})
+	})
+
+	Describe("Hunting Entries by Hash", func() {
+		It("Should find the current user's entry by the digest of its executable", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			data, err := os.ReadFile(testCommand)
+			Expect(err).To(BeNil())
+			digest := sha256.Sum256(data)
+
+			user, err := windows.GetCurrentProcessToken().GetTokenUser()
+			Expect(err).To(BeNil())
+			sid := user.User.Sid.String()
+
+			matches, err := winstartupreg.FindByHashAllUsers(hex.EncodeToString(digest[:]))
+			Expect(err).To(BeNil())
+			Expect(matches).To(HaveKey(sid))
+			Expect(matches[sid]).To(ContainElement(HaveField("Name", testAppName)))
+		})
+
+		It("Should reject a malformed digest", func() {
+			_, err := winstartupreg.FindByHashAllUsers("not-a-digest")
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Finding Reparse Point Entries", func() {
+		It("Should flag entries whose path goes through a directory junction", func() {
+			root := GinkgoT().TempDir()
+			junction := filepath.Join(root, "link")
+			out, err := exec.Command("cmd", "/c", "mklink", "/J", junction, filepath.Dir(testCommand)).CombinedOutput()
+			Expect(err).To(BeNil(), string(out))
+
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			found, err := winstartupreg.FindReparsePointEntries()
+			Expect(err).To(BeNil())
+			Expect(found).ToNot(ContainElement(HaveField("Name", testAppName)))
+
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: filepath.Join(junction, filepath.Base(testCommand))}, winstartupreg.CurrentUserRun)).To(Succeed())
+			found, err = winstartupreg.FindReparsePointEntries()
+			Expect(err).To(BeNil())
+			Expect(found).To(ContainElement(HaveField("Name", testAppName)))
+		})
+	})
+
+	Describe("Grouping Entries by Install Time", func() {
+		It("Should put entries written together into one group", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName + "Companion", Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			DeferCleanup(func() {
+				_ = winstartupreg.RemoveStartupEntry(testAppName+"Companion", winstartupreg.CurrentUserRun)
+			})
+
+			groups, err := winstartupreg.EntriesGroupedByApproxInstallTime(time.Minute)
+			Expect(err).To(BeNil())
+
+			var group []winstartupreg.StartupItem
+			for _, items := range groups {
+				for _, item := range items {
+					if item.Name == testAppName {
+						group = items
+					}
+				}
+			}
+			Expect(group).To(ContainElement(HaveField("Name", testAppName+"Companion")))
+		})
+
+		It("Should reject a negative window", func() {
+			_, err := winstartupreg.EntriesGroupedByApproxInstallTime(-time.Second)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Checking Architecture Compatibility", func() {
+		It("Should accept the running test binary", func() {
+			self, err := os.Executable()
+			Expect(err).To(BeNil())
+
+			compatible, exeArch, osArch, err := winstartupreg.CheckArchitectureCompatibility(winstartupreg.StartupEntry{Name: testAppName, Command: self})
+			Expect(err).To(BeNil())
+			Expect(compatible).To(BeTrue())
+			Expect(exeArch).To(BeElementOf("x86", "x64", "arm64"))
+			Expect(osArch).ToNot(BeEmpty())
+		})
+
+		It("Should fail for files that are not PE executables", func() {
+			_, _, _, err := winstartupreg.CheckArchitectureCompatibility(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Journaling Changes", func() {
+		var journalPath string
+
+		BeforeEach(func() {
+			journalPath = filepath.Join(GinkgoT().TempDir(), "journal.jsonl")
+			winstartupreg.SetJournal(journalPath)
+			DeferCleanup(func() {
+				winstartupreg.SetJournal("")
+			})
+		})
+
+		It("Should record adds, disables, enables and removes in order", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.DisableStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.EnableStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			entries, err := winstartupreg.ReadJournal(journalPath)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(4))
+			Expect(entries[0]).To(And(HaveField("Operation", winstartupreg.JournalAdd), HaveField("After", testCommand)))
+			Expect(entries[1]).To(And(HaveField("Operation", winstartupreg.JournalDisable), HaveField("After", "disabled")))
+			Expect(entries[2]).To(And(HaveField("Operation", winstartupreg.JournalEnable), HaveField("After", "enabled")))
+			Expect(entries[3]).To(And(HaveField("Operation", winstartupreg.JournalRemove), HaveField("Before", testCommand)))
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`, registry.SET_VALUE)
+			if err == nil {
+				_ = k.DeleteValue(testAppName)
+				k.Close()
+			}
+		})
+
+		It("Should record writes made by ApplyPlan and CompareAndSwap", func() {
+			DeferCleanup(removeTestMetadata, testAppName)
+
+			desired := []winstartupreg.StartupEntry{{Name: testAppName, Command: testCommand}}
+			plan, err := winstartupreg.PlanSync("journal-test", desired, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(winstartupreg.ApplyPlan(plan)).To(Succeed())
+
+			swapped, err := winstartupreg.CompareAndSwap(testAppName, testCommand, testCommand+" --tray", winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(swapped).To(BeTrue())
+
+			// A swap that does not happen is not recorded
+			swapped, err = winstartupreg.CompareAndSwap(testAppName, "stale", testCommand, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(swapped).To(BeFalse())
+
+			plan, err = winstartupreg.PlanSync("journal-test", nil, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(winstartupreg.ApplyPlan(plan)).To(Succeed())
+
+			entries, err := winstartupreg.ReadJournal(journalPath)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(3))
+			Expect(entries[0]).To(And(HaveField("Operation", winstartupreg.JournalAdd), HaveField("Name", testAppName), HaveField("Before", ""), HaveField("After", testCommand)))
+			Expect(entries[1]).To(And(HaveField("Operation", winstartupreg.JournalAdd), HaveField("Before", testCommand), HaveField("After", testCommand+" --tray")))
+			Expect(entries[2]).To(And(HaveField("Operation", winstartupreg.JournalRemove), HaveField("Before", testCommand+" --tray")))
+		})
+
+		It("Should record every ordinal a numbered insert changes", func() {
+			// The policy key is shared with the developer's machine, only run on an empty one
+			items, err := winstartupreg.ListStartupItems(winstartupreg.CurrentUserPolicyRun)
+			Expect(err).To(BeNil())
+			if len(items) > 0 {
+				Skip("the current user's policy Run key already has values")
+			}
+			DeferCleanup(func() {
+				_ = registry.DeleteKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`)
+			})
+
+			Expect(winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: testCommand}, 1, winstartupreg.CurrentUserPolicyRun)).To(Succeed())
+			Expect(winstartupreg.AddStartupEntryAt(winstartupreg.StartupEntry{Command: testCommand, Args: []string{"--first"}}, 1, winstartupreg.CurrentUserPolicyRun)).To(Succeed())
+
+			entries, err := winstartupreg.ReadJournal(journalPath)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(3))
+			Expect(entries[0]).To(And(HaveField("Name", "1"), HaveField("After", testCommand)))
+			Expect(entries[1]).To(And(HaveField("Name", "1"), HaveField("Before", testCommand), HaveField("After", testCommand+" --first")))
+			Expect(entries[2]).To(And(HaveField("Name", "2"), HaveField("Before", ""), HaveField("After", testCommand)))
+		})
+
+		It("Should stop the operation when a required journal cannot be opened", func() {
+			winstartupreg.SetJournal(filepath.Join(journalPath, "missing", "journal.jsonl"), winstartupreg.WithJournalRequired())
+
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)).ToNot(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+		})
+	})
+
+	Describe("Pinning Entries", func() {
+		BeforeEach(func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.PinEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			removeTestMetadata(testAppName)
+		})
+
+		It("Should refuse to remove a pinned entry without force", func() {
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(MatchError(winstartupreg.ErrEntryPinned))
+			Expect(winstartupreg.SafeRemoveStartupEntry(testAppName)).To(MatchError(winstartupreg.ErrEntryPinned))
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKey(testAppName))
+
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.WithForce())).To(Succeed())
+		})
+
+		It("Should not carry the pin of a force-removed entry over to a new one", func() {
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.WithForce())).To(Succeed())
+			_, err := winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrNoMetadata))
+
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should quarantine nothing when one of the entries is pinned", func() {
+			quarantinePath := filepath.Join(GinkgoT().TempDir(), "quarantine.json")
+			err := winstartupreg.QuarantineToFile([]string{testAppName}, quarantinePath)
+			Expect(err).To(MatchError(winstartupreg.ErrEntryPinned))
+			Expect(quarantinePath).ToNot(BeAnExistingFile())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKey(testAppName))
+
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.WithForce())).To(Succeed())
+		})
+
+		It("Should allow removal again after unpinning", func() {
+			Expect(winstartupreg.UnpinEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should refuse to pin an entry that does not exist", func() {
+			Expect(winstartupreg.PinEntry("NonExistentApp", winstartupreg.CurrentUserRun)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Expanding for Another User", func() {
+		It("Should expand the current user's variables from their hive", func() {
+			user, err := windows.GetCurrentProcessToken().GetTokenUser()
+			Expect(err).To(BeNil())
+
+			expanded, err := winstartupreg.ExpandForUser(`%USERPROFILE%\app.exe %SystemRoot%`, user.User.Sid.String())
+			Expect(err).To(BeNil())
+			Expect(strings.ToLower(expanded)).To(Equal(strings.ToLower(os.Getenv("USERPROFILE") + `\app.exe ` + os.Getenv("SystemRoot"))))
+		})
+
+		It("Should fail for a user whose hive is not loaded", func() {
+			_, err := winstartupreg.ExpandForUser(`%APPDATA%\app.exe`, "S-1-5-21-0-0-0-999999")
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Watching the Startup Folders", func() {
+		It("Should report one event per shortcut change and close on cancel", func() {
+			dir, err := windows.KnownFolderPath(windows.FOLDERID_Startup, 0)
+			Expect(err).To(BeNil())
+			shortcut := filepath.Join(dir, testAppName+".lnk")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events, err := winstartupreg.WatchStartupFolders(ctx)
+			Expect(err).To(BeNil())
+
+			// Several writes in a row are delivered as a single addition
+			Expect(os.WriteFile(shortcut, []byte("first"), 0o644)).To(Succeed())
+			Expect(os.WriteFile(shortcut, []byte("second"), 0o644)).To(Succeed())
+			var event winstartupreg.FolderChangeEvent
+			Eventually(events, 5*time.Second).Should(Receive(&event))
+			Expect(event.Name).To(Equal(testAppName + ".lnk"))
+			Expect(event.Source).To(Equal(winstartupreg.SourceUserStartupFolder))
+			Expect(event.Op).To(Equal(winstartupreg.FolderShortcutAdded))
+			Consistently(events, 500*time.Millisecond).ShouldNot(Receive())
+
+			Expect(os.Remove(shortcut)).To(Succeed())
+			Eventually(events, 5*time.Second).Should(Receive(&event))
+			Expect(event.Op).To(Equal(winstartupreg.FolderShortcutRemoved))
+
+			cancel()
+			Eventually(events, 5*time.Second).Should(BeClosed())
+		})
+	})
+
+	Describe("Toggling Start with Windows", func() {
+		AfterEach(func() {
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`, registry.SET_VALUE)
+			if err == nil {
+				_ = k.DeleteValue(testAppName)
+				k.Close()
+			}
+		})
+
+		It("Should register the running executable and remove it again", func() {
+			toggle := winstartupreg.StartupToggle(testAppName)
+			Expect(toggle.Enabled()).To(BeFalse())
+
+			Expect(toggle.SetEnabled(true)).To(Succeed())
+			Expect(toggle.Enabled()).To(BeTrue())
+
+			Expect(toggle.SetEnabled(false)).To(Succeed())
+			Expect(toggle.Enabled()).To(BeFalse())
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+
+			Expect(toggle.Disable()).To(Succeed())
+		})
+
+		It("Should keep the Run value when disabling through StartupApproved", func() {
+			toggle := winstartupreg.StartupToggle(testAppName, winstartupreg.WithDisablePolicy(winstartupreg.DisableByApproval))
+			Expect(toggle.Enable()).To(Succeed())
+			Expect(toggle.Disable()).To(Succeed())
+			Expect(toggle.Enabled()).To(BeFalse())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKey(testAppName))
+
+			Expect(toggle.Enable()).To(Succeed())
+			Expect(toggle.Enabled()).To(BeTrue())
+		})
+	})
+
+	Describe("Copying and Moving Entries", func() {
+		var copyName string
+
+		BeforeEach(func() {
+			copyName = testAppName + "Copy"
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.SetEntryMetadata(testAppName, winstartupreg.CurrentUserRun, winstartupreg.EntryMetadata{Owner: "test-suite", Pinned: true})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			removeTestMetadata(testAppName)
+			removeTestMetadata(copyName)
+			_ = winstartupreg.RemoveStartupEntry(copyName, winstartupreg.CurrentUserRun, winstartupreg.WithForce())
+		})
+
+		It("Should duplicate the metadata when copying under a new name", func() {
+			Expect(winstartupreg.CopyStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.CurrentUserRun, copyName)).To(Succeed())
+
+			for _, name := range []string{testAppName, copyName} {
+				metadata, err := winstartupreg.GetEntryMetadataTyped(name, winstartupreg.CurrentUserRun)
+				Expect(err).To(BeNil())
+				Expect(metadata.Owner).To(Equal("test-suite"))
+				Expect(metadata.Pinned).To(BeTrue())
+			}
+		})
+
+		It("Should replace metadata left at the destination by an earlier entry", func() {
+			Expect(winstartupreg.UnpinEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			// Orphaned metadata of a gone entry, as left by an older version or another tool
+			k, _, err := registry.CreateKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run\__meta\`+copyName, registry.SET_VALUE)
+			Expect(err).To(BeNil())
+			Expect(k.SetStringValue("", `{"schemaVersion":1,"owner":"old-tool","pinned":true,"legacy":1}`)).To(Succeed())
+			k.Close()
+
+			Expect(winstartupreg.MoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.CurrentUserRun, copyName)).To(Succeed())
+
+			metadata, err := winstartupreg.GetEntryMetadataTyped(copyName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(metadata.Owner).To(Equal("test-suite"))
+			Expect(metadata.Pinned).To(BeFalse())
+			Expect(metadata.Extra).To(BeEmpty())
+			Expect(winstartupreg.RemoveStartupEntry(copyName, winstartupreg.CurrentUserRun)).To(Succeed())
+		})
+
+		It("Should re-key the metadata and remove the source when moving", func() {
+			Expect(winstartupreg.MoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.CurrentUserRun, copyName)).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+			Expect(entries).To(HaveKeyWithValue(copyName, testCommand))
+
+			_, err = winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrNoMetadata))
+			metadata, err := winstartupreg.GetEntryMetadataTyped(copyName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(metadata.Pinned).To(BeTrue())
+		})
+
+		It("Should move the approval state with the entry", func() {
+			DeferCleanup(removeTestApproval, testAppName)
+			DeferCleanup(removeTestApproval, copyName)
+			Expect(winstartupreg.DisableStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			Expect(winstartupreg.MoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.CurrentUserRun, copyName)).To(Succeed())
+
+			enabled, err := winstartupreg.IsStartupEntryEnabled(copyName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeFalse())
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`, registry.QUERY_VALUE)
+			Expect(err).To(BeNil())
+			defer k.Close()
+			_, _, err = k.GetBinaryValue(testAppName)
+			Expect(err).To(MatchError(registry.ErrNotExist))
+		})
+
+		It("Should not overwrite an existing destination", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: copyName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			Expect(winstartupreg.MoveStartupEntry(testAppName, winstartupreg.CurrentUserRun, winstartupreg.CurrentUserRun, copyName)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Adding Startup Entries Through a Registry View", func() {
+		It("Should share HKCU entries between both views", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			Expect(winstartupreg.AddStartupEntryWithView(entry, winstartupreg.CurrentUserRun, winstartupreg.View32)).To(Succeed())
+
+			items, err := winstartupreg.ListStartupItemsInView(winstartupreg.CurrentUserRun, winstartupreg.View64)
+			Expect(err).To(BeNil())
+			Expect(items).To(ContainElement(HaveField("Name", testAppName)))
+		})
+
+		It("Should write HKLM entries to the requested view only", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			if err := winstartupreg.AddStartupEntryWithView(entry, winstartupreg.AllUsersRun, winstartupreg.View32); err != nil {
+				Skip("cannot write AllUsersRun: " + err.Error())
+			}
+			DeferCleanup(func() {
+				_ = winstartupreg.RemoveStartupEntryWithView(testAppName, winstartupreg.AllUsersRun, winstartupreg.View32)
+			})
+
+			items32, err := winstartupreg.ListStartupItemsInView(winstartupreg.AllUsersRun, winstartupreg.View32)
+			Expect(err).To(BeNil())
+			Expect(items32).To(ContainElement(HaveField("Name", testAppName)))
+
+			items64, err := winstartupreg.ListStartupItemsInView(winstartupreg.AllUsersRun, winstartupreg.View64)
+			Expect(err).To(BeNil())
+			Expect(items64).ToNot(ContainElement(HaveField("Name", testAppName)))
+		})
+	})
+
+	Describe("Managing Startup Folder Shortcuts", func() {
+		It("Should create, list and remove a shortcut", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand, Args: []string{"--tray"}}
+			Expect(winstartupreg.AddStartupShortcut(entry)).To(Succeed())
+			DeferCleanup(func() {
+				_ = winstartupreg.RemoveStartupShortcut(testAppName)
+			})
+
+			shortcuts, err := winstartupreg.ListStartupShortcuts()
+			Expect(err).To(BeNil())
+			Expect(shortcuts).To(HaveKey(testAppName))
+			Expect(winstartupreg.ParseStartupEntry(testAppName, shortcuts[testAppName])).To(Equal(entry))
+
+			Expect(winstartupreg.RemoveStartupShortcut(testAppName)).To(Succeed())
+			Expect(winstartupreg.RemoveStartupShortcut(testAppName)).To(MatchError(winstartupreg.ErrEntryNotFound))
+
+			shortcuts, err = winstartupreg.ListStartupShortcuts()
+			Expect(err).To(BeNil())
+			Expect(shortcuts).ToNot(HaveKey(testAppName))
+		})
+
+		It("Should not remove shortcuts outside the Startup folder", func() {
+			startup, err := windows.KnownFolderPath(windows.FOLDERID_Startup, 0)
+			Expect(err).To(BeNil())
+			outside := filepath.Join(filepath.Dir(startup), testAppName+".lnk")
+			Expect(os.WriteFile(outside, nil, 0o644)).To(Succeed())
+			DeferCleanup(os.Remove, outside)
+
+			err = winstartupreg.RemoveStartupShortcut(`..\` + testAppName)
+			Expect(err).To(MatchError(ContainSubstring("cannot be used as a file name")))
+			Expect(outside).To(BeAnExistingFile())
+		})
+	})
+
+	Describe("Backing Up Startup Entries", func() {
+		It("Should restore an exported entry exactly", func() {
+			GinkgoT().Setenv("WINSTARTUPREG_TEST_DIR", filepath.Dir(testCommand))
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: `%WINSTARTUPREG_TEST_DIR%\` + filepath.Base(testCommand), Args: []string{"--tray"}}
+			Expect(winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun, winstartupreg.WithExpandType())).To(Succeed())
+
+			stored, _, err := winstartupreg.GetStartupEntryCanonical(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+
+			var backup strings.Builder
+			Expect(winstartupreg.ExportStartupEntries(&backup)).To(Succeed())
+			Expect(backup.String()).To(ContainSubstring(`"CurrentUserRun"`))
+			Expect(winstartupreg.RemoveStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			written, err := winstartupreg.ImportStartupEntries(strings.NewReader(backup.String()), false)
+			Expect(err).To(BeNil())
+			Expect(written).To(Equal(1))
+
+			items, err := winstartupreg.ListStartupItems(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(items).To(ContainElement(And(
+				HaveField("Name", testAppName),
+				HaveField("Command", stored),
+				HaveField("ValueType", uint32(registry.EXPAND_SZ)),
+			)))
+
+			// Everything exists now, so nothing is written without overwrite
+			written, err = winstartupreg.ImportStartupEntries(strings.NewReader(backup.String()), false)
+			Expect(err).To(BeNil())
+			Expect(written).To(BeZero())
+		})
+	})
+
+	Describe("Checking Elevation", func() {
+		It("Should refuse all-users writes up front without elevation", func() {
+			elevated, err := winstartupreg.IsElevated()
+			Expect(err).To(BeNil())
+			Expect(elevated).To(Equal(windows.GetCurrentProcessToken().IsElevated()))
+			if elevated {
+				Skip("the test process is elevated")
+			}
+
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+			err = winstartupreg.AddStartupEntry(entry, winstartupreg.AllUsersRun)
+			Expect(err).To(MatchError(winstartupreg.ErrElevationRequired))
+			Expect(err).To(MatchError(winstartupreg.ErrAccessDenied))
+
+			// No launcher script is written for a location that cannot be registered
+			err = winstartupreg.AddNoWindowStartupEntry(entry, winstartupreg.AllUsersRun)
+			Expect(err).To(MatchError(winstartupreg.ErrElevationRequired))
+
+			// Approval state of all-users entries lives under HKLM too
+			err = winstartupreg.DisableStartupEntry(testAppName, winstartupreg.AllUsersRun)
+			Expect(err).To(MatchError(winstartupreg.ErrElevationRequired))
+			Expect(err).To(MatchError(winstartupreg.ErrAccessDenied))
+
+			swapped, err := winstartupreg.CompareAndSwap(testAppName, "", testCommand, winstartupreg.AllUsersRun)
+			Expect(swapped).To(BeFalse())
+			Expect(err).To(MatchError(winstartupreg.ErrElevationRequired))
+			Expect(err).To(MatchError(winstartupreg.ErrAccessDenied))
+
+			// Listing only needs read access
+			_, err = winstartupreg.ListStartupEntries(winstartupreg.AllUsersRun)
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("Renaming Startup Entries", func() {
+		renamed := testAppName + "_renamed"
+
+		BeforeEach(func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}, winstartupreg.CurrentUserRun)).To(Succeed())
+			DeferCleanup(func() {
+				_ = winstartupreg.RemoveStartupEntry(renamed, winstartupreg.CurrentUserRun, winstartupreg.WithForce())
+				removeTestMetadata(testAppName)
+				removeTestMetadata(renamed)
+			})
+		})
+
+		It("Should move the command and metadata to the new name", func() {
+			Expect(winstartupreg.PinEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			Expect(winstartupreg.RenameStartupEntry(testAppName, renamed, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).ToNot(HaveKey(testAppName))
+			Expect(entries).To(HaveKeyWithValue(renamed, testCommand))
+
+			metadata, err := winstartupreg.GetEntryMetadataTyped(renamed, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(metadata.Pinned).To(BeTrue())
+			_, err = winstartupreg.GetEntryMetadataTyped(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrNoMetadata))
+		})
+
+		It("Should keep a disabled entry disabled under the new name", func() {
+			DeferCleanup(removeTestApproval, testAppName)
+			DeferCleanup(removeTestApproval, renamed)
+			Expect(winstartupreg.DisableStartupEntry(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			Expect(winstartupreg.RenameStartupEntry(testAppName, renamed, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			enabled, err := winstartupreg.IsStartupEntryEnabled(renamed, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeFalse())
+
+			// No orphan blob stays behind under the old name
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`, registry.QUERY_VALUE)
+			Expect(err).To(BeNil())
+			defer k.Close()
+			_, _, err = k.GetBinaryValue(testAppName)
+			Expect(err).To(MatchError(registry.ErrNotExist))
+		})
+
+		It("Should only replace an existing name with WithOverwrite", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{Name: renamed, Command: testCommand, Args: []string{"--old"}}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			Expect(winstartupreg.RenameStartupEntry(testAppName, renamed, winstartupreg.CurrentUserRun)).To(MatchError(ContainSubstring("already exists")))
+			Expect(winstartupreg.RenameStartupEntry(testAppName, renamed, winstartupreg.CurrentUserRun, winstartupreg.WithOverwrite())).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveKeyWithValue(renamed, testCommand))
+		})
+
+		It("Should report a missing entry with ErrEntryNotFound", func() {
+			err := winstartupreg.RenameStartupEntry(testAppName+"_missing", renamed, winstartupreg.CurrentUserRun)
+			Expect(err).To(MatchError(winstartupreg.ErrEntryNotFound))
+		})
+	})
+
+	Describe("Watching Startup Entries", func() {
+		It("should send the new entry set when the key changes and close on cancel", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			updates, err := winstartupreg.WatchStartupEntries(ctx, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			var entries []winstartupreg.StartupEntry
+			Eventually(updates, 5*time.Second).Should(Receive(&entries))
+			names := make([]string, len(entries))
+			for i, entry := range entries {
+				names[i] = entry.Name
+			}
+			Expect(names).To(ContainElement(testAppName))
+
+			cancel()
+			Eventually(updates, 5*time.Second).Should(BeClosed())
+		})
+	})
+
+	Describe("Adding and Removing Startup Entries in Bulk", func() {
+		secondName := testAppName + "Second"
+
+		AfterEach(func() {
+			_ = winstartupreg.RemoveStartupEntry(secondName, winstartupreg.CurrentUserRun)
+		})
+
+		It("should add all entries and remove them again", func() {
+			Expect(winstartupreg.AddStartupEntries([]winstartupreg.StartupEntry{
+				{Name: testAppName, Command: testCommand},
+				{Name: secondName, Command: testCommand},
+			}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			for _, name := range []string{testAppName, secondName} {
+				exists, err := winstartupreg.EntryExists(name, winstartupreg.CurrentUserRun)
+				Expect(err).To(BeNil())
+				Expect(exists).To(BeTrue())
+			}
+
+			// Names that are not there are skipped
+			Expect(winstartupreg.RemoveStartupEntries([]string{testAppName, "NonExistentApp", secondName}, winstartupreg.CurrentUserRun)).To(Succeed())
+			for _, name := range []string{testAppName, secondName} {
+				exists, err := winstartupreg.EntryExists(name, winstartupreg.CurrentUserRun)
+				Expect(err).To(BeNil())
+				Expect(exists).To(BeFalse())
+			}
+		})
+
+		It("should roll back the added entries when one fails", func() {
+			original := filepath.Join(GinkgoT().TempDir(), "original.exe")
+			Expect(os.WriteFile(original, []byte("MZ"), 0o755)).To(Succeed())
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: original,
+			}, winstartupreg.CurrentUserRun)).To(Succeed())
+			before, _, err := winstartupreg.GetStartupEntryCanonical(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+
+			err = winstartupreg.AddStartupEntries([]winstartupreg.StartupEntry{
+				{Name: testAppName, Command: testCommand},
+				{Name: secondName, Command: testCommand},
+				{Name: "BrokenApp", Command: `C:\Path\That\Does\Not\Exist.exe`},
+			}, winstartupreg.CurrentUserRun)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("'BrokenApp'"))
+
+			after, _, err := winstartupreg.GetStartupEntryCanonical(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(after).To(Equal(before))
+			exists, err := winstartupreg.EntryExists(secondName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Describe("Listing Startup Entries for All Users", func() {
+		It("should include the entries of the current user under their SID", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			token := windows.GetCurrentProcessToken()
+			user, err := token.GetTokenUser()
+			Expect(err).To(BeNil())
+			sid := user.User.Sid.String()
+
+			// Hives of other users may be unreadable without elevation
+			users, _ := winstartupreg.ListStartupEntriesForAllUsers()
+			Expect(users).To(HaveKey(sid))
+			Expect(users[sid]).To(HaveKey(testAppName))
+			Expect(users).NotTo(HaveKey("S-1-5-18"))
+
+			account, err := winstartupreg.AccountNameForSID(sid)
+			Expect(err).To(BeNil())
+			Expect(strings.ToLower(account)).To(HaveSuffix(strings.ToLower(`\` + os.Getenv("USERNAME"))))
+		})
+
+		It("should reject a malformed SID", func() {
+			_, err := winstartupreg.AccountNameForSID("not-a-sid")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Registering the Running Executable", func() {
+		It("should register and unregister the executable under the given name", func() {
+			self, err := os.Executable()
+			Expect(err).To(BeNil())
+			if resolved, err := filepath.EvalSymlinks(self); err == nil {
+				self = resolved
+			}
+
+			Expect(winstartupreg.RegisterSelf(testAppName, winstartupreg.CurrentUserRun)).To(Succeed())
+			entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(winstartupreg.CommandExecutable(entries[testAppName])).To(Equal(self))
+
+			Expect(winstartupreg.UnregisterSelf(testAppName)).To(Succeed())
+			exists, err := winstartupreg.EntryExists(testAppName, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should default the name to the executable name without extension", func() {
+			self, err := os.Executable()
+			Expect(err).To(BeNil())
+			name := strings.TrimSuffix(filepath.Base(self), filepath.Ext(self))
+
+			Expect(winstartupreg.RegisterSelf("", winstartupreg.CurrentUserRun)).To(Succeed())
+			DeferCleanup(func() {
+				_ = winstartupreg.RemoveStartupEntry(name, winstartupreg.CurrentUserRun)
+			})
+			exists, err := winstartupreg.EntryExists(name, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeTrue())
+
+			Expect(winstartupreg.UnregisterSelf("")).To(Succeed())
+			exists, err = winstartupreg.EntryExists(name, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Describe("Listing Startup Entries in Detail", func() {
+		It("should report the value type and location of each entry", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			entries, err := winstartupreg.ListStartupEntriesDetailed(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(ContainElement(winstartupreg.StartupEntryInfo{
+				Name:         testAppName,
+				Command:      testCommand,
+				ValueType:    registry.SZ,
+				RegistryType: winstartupreg.CurrentUserRun,
+			}))
+			plain, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(len(plain)))
+		})
+
+		It("should return every location as one sorted slice", func() {
+			Expect(winstartupreg.AddStartupEntry(winstartupreg.StartupEntry{
+				Name:    testAppName,
+				Command: testCommand,
+			}, winstartupreg.CurrentUserRun)).To(Succeed())
+
+			entries, err := winstartupreg.ListAllStartupEntriesDetailed()
+			Expect(err).To(BeNil())
+			Expect(entries).NotTo(BeEmpty())
+			for i := 1; i < len(entries); i++ {
+				previous, current := entries[i-1], entries[i]
+				Expect(previous.RegistryType < current.RegistryType ||
+					previous.RegistryType == current.RegistryType && previous.Name < current.Name).To(BeTrue())
+			}
+		})
+	})
+
+	Describe("Adding a Startup Entry Only When It Changed", func() {
+		It("should write only when the entry is absent or different", func() {
+			entry := winstartupreg.StartupEntry{Name: testAppName, Command: testCommand}
+
+			changed, err := winstartupreg.AddStartupEntryIfChanged(entry, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(changed).To(BeTrue())
+
+			k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.QUERY_VALUE)
+			Expect(err).To(BeNil())
+			defer k.Close()
+			info, err := k.Stat()
+			Expect(err).To(BeNil())
+			lastWrite := info.ModTime()
+
+			// An up to date entry leaves the key untouched
+			changed, err = winstartupreg.AddStartupEntryIfChanged(entry, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(changed).To(BeFalse())
+			info, err = k.Stat()
+			Expect(err).To(BeNil())
+			Expect(info.ModTime()).To(Equal(lastWrite))
+
+			// A different value type counts as a change
+			changed, err = winstartupreg.AddStartupEntryIfChanged(entry, winstartupreg.CurrentUserRun, winstartupreg.WithExpandType())
+			Expect(err).To(BeNil())
+			Expect(changed).To(BeTrue())
+			_, valType, err := k.GetStringValue(testAppName)
+			Expect(err).To(BeNil())
+			Expect(valType).To(Equal(uint32(registry.EXPAND_SZ)))
+
+			entry.Args = []string{"--minimized"}
+			changed, err = winstartupreg.AddStartupEntryIfChanged(entry, winstartupreg.CurrentUserRun)
+			Expect(err).To(BeNil())
+			Expect(changed).To(BeTrue())
+		})
+	})
+
+	Describe("Listing Logon Scheduled Tasks", func() {
+		It("Should describe every listed task", func() {
+			tasks, err := winstartupreg.ListLogonScheduledTasks()
+			Expect(err).To(BeNil())
+
+			for _, task := range tasks {
+				Expect(task.Path).To(HavePrefix(`\`))
+				Expect(task.Path).To(HaveSuffix(task.Name))
+			}
+		})
+	})
+
+	Describe("Safe Remove Startup Entry", func() {
+		Context("When entry exists", func() {
+			BeforeEach(func() {
+				// Add an entry before each test
+				entry := winstartupreg.StartupEntry{
+					Name:    testAppName,
+					Command: testCommand,
+				}
+
+				_ = winstartupreg.AddStartupEntry(entry, winstartupreg.CurrentUserRun)
+			})
+
+			It("Should safely remove the startup entry", func() {
+				err1 := winstartupreg.SafeRemoveStartupEntry(testAppName)
+				Expect(err1).To(BeNil())
+
+				// Verify the entry was removed
+				entries, err := winstartupreg.ListStartupEntries(winstartupreg.CurrentUserRun)
+				Expect(err).To(BeNil())
+				Expect(entries).ToNot(HaveKey(testAppName))
+			})
+		})
+
+		Context("When entry does not exist", func() {
+			It("Should return an error", func() {
+				err := winstartupreg.SafeRemoveStartupEntry("TestApp")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+})
+
+// Remove the sidecar metadata a test attached to a CurrentUserRun entry
+func removeTestMetadata(name string) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run\__meta`, registry.ALL_ACCESS)
+	if err == nil {
+		_ = registry.DeleteKey(k, name)
+		k.Close()
+	}
+}
+
+// removeTestApproval deletes the CurrentUserRun StartupApproved blob of name
+func removeTestApproval(name string) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`, registry.SET_VALUE)
+	if err == nil {
+		_ = k.DeleteValue(name)
+		k.Close()
+	}
+}
 
 // Create a temporary executable for testing
 func createTempExecutable() (string, error) {
@@ -0,0 +1,627 @@
+//go:build windows
+
+package winstartupreg
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// getRegistryPath returns the full registry path and root key for a given startup type
+func getRegistryPath(registryType StartupRegistryType) (string, registry.Key) {
+	switch registryType {
+	case CurrentUserRun:
+		return `Software\Microsoft\Windows\CurrentVersion\Run`, registry.CURRENT_USER
+	case CurrentUserRunOnce:
+		return `Software\Microsoft\Windows\CurrentVersion\RunOnce`, registry.CURRENT_USER
+	case AllUsersRun:
+		return `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, registry.LOCAL_MACHINE
+	case AllUsersRunOnce:
+		return `SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnce`, registry.LOCAL_MACHINE
+	case CurrentUserPolicyRun:
+		return `Software\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`, registry.CURRENT_USER
+	case AllUsersPolicyRun:
+		return `SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`, registry.LOCAL_MACHINE
+	default:
+		return `Software\Microsoft\Windows\CurrentVersion\Run`, registry.CURRENT_USER
+	}
+}
+
+// AddStartupEntry adds an application to Windows startup registry
+func AddStartupEntry(entry StartupEntry, registryType StartupRegistryType, opts ...AddOption) error {
+	return AddStartupEntryWithView(entry, registryType, DefaultView, opts...)
+}
+
+// AddStartupEntryWithView adds an entry through an explicit registry view. A
+// 32-bit process writing AllUsersRun lands in WOW6432Node by default, View64
+// writes the key a 64-bit process sees instead. The view has no effect on the
+// HKCU locations, which both views share
+func AddStartupEntryWithView(entry StartupEntry, registryType StartupRegistryType, view RegistryView, opts ...AddOption) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if err := requireElevation(registryType); err != nil {
+		return err
+	}
+
+	options := addOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Validate input
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	// Open the journal before anything changes so a required journal can stop the add
+	journal, err := openJournal()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	var before string
+	if journal != nil {
+		before = currentCommand(entry.Name, registryType, view)
+	}
+
+	// Work out the stored command line and its value type
+	command, valType, err := addCommand(entry, options)
+	if err != nil {
+		return err
+	}
+	if valType == registry.EXPAND_SZ {
+		// Recorded in the journal by the write itself
+		return writeStringValueInView(entry.Name, command, registry.EXPAND_SZ, registryType, view)
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key with write access, setting the value needs nothing more
+	k, err := registry.OpenKey(rootKey, keyPath, registry.SET_VALUE|uint32(view))
+	if err != nil {
+		return fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	// Set the registry value
+	err = k.SetStringValue(entry.Name, command)
+	if err != nil {
+		return fmt.Errorf("failed to set registry value: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+
+	return journal.record(JournalEntry{Operation: JournalAdd, Location: registryType, Name: entry.Name, Before: before, After: command})
+}
+
+// AddStartupEntryIfChanged adds an entry only when the stored value differs from
+// what AddStartupEntry would write, in command or value type, or is absent. An
+// entry that is already up to date is left alone, so its key keeps its last write
+// time and watchers are not notified. Reports whether a write happened
+func AddStartupEntryIfChanged(entry StartupEntry, registryType StartupRegistryType, opts ...AddOption) (changed bool, err error) {
+	if err := checkWritable(); err != nil {
+		return false, err
+	}
+
+	options := addOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Validate input
+	if err := entry.Validate(); err != nil {
+		return false, err
+	}
+
+	command, valType, err := addCommand(entry, options)
+	if err != nil {
+		return false, err
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Compare with the current value, a missing key or value needs the write
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	switch {
+	case err == nil:
+		current, currentType, err := k.GetStringValue(entry.Name)
+		k.Close()
+		if err == nil && current == command && currentType == valType {
+			return false, nil
+		}
+		if err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return false, fmt.Errorf("failed to read startup entry '%s' in %s: %w", entry.Name, registryType, classifyRegistryError(err, ErrEntryNotFound))
+		}
+	case !errors.Is(err, registry.ErrNotExist):
+		return false, fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+
+	if err := AddStartupEntry(entry, registryType, opts...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// addCommand resolves the command line AddStartupEntry stores for an entry and
+// its value type. Commands that reference a defined variable are stored
+// unexpanded as REG_EXPAND_SZ when asked for, the arguments are not part of the
+// executable path that is validated
+func addCommand(entry StartupEntry, options addOptions) (string, uint32, error) {
+	if options.expandType || options.autoExpandType {
+		if expanded := expandVariables(entry.Command, os.LookupEnv); options.expandType || expanded != entry.Command {
+			fullPath, err := resolveCommand(expanded)
+			if err != nil {
+				return "", 0, err
+			}
+			if options.validatePE {
+				if err := checkPEImage(fullPath); err != nil {
+					return "", 0, err
+				}
+			}
+			return entryCommandLine(entry.Command, expanded, entry.Args), registry.EXPAND_SZ, nil
+		}
+	}
+
+	// Normalize and validate command path
+	fullPath, err := resolveCommand(entry.Command)
+	if err != nil {
+		return "", 0, err
+	}
+	if options.validatePE {
+		if err := checkPEImage(fullPath); err != nil {
+			return "", 0, err
+		}
+	}
+	return entryCommandLine(fullPath, fullPath, entry.Args), registry.SZ, nil
+}
+
+// AddStartupEntryPreferred adds an entry to the first location of preference that
+// accepts it, for example AllUsersRun when running elevated with CurrentUserRun as
+// the fallback. Locations that fail with access denied are skipped, any other
+// failure stops the search. The location written to is returned; when none
+// accepted the entry, the error lists the failure of each location tried
+func AddStartupEntryPreferred(entry StartupEntry, preference []StartupRegistryType) (used StartupRegistryType, err error) {
+	if len(preference) == 0 {
+		return 0, fmt.Errorf("no preferred locations given")
+	}
+
+	var errs []error
+	for _, registryType := range preference {
+		err := AddStartupEntry(entry, registryType)
+		if err == nil {
+			return registryType, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", registryType, err))
+		if !errors.Is(err, ErrAccessDenied) {
+			break
+		}
+	}
+
+	return 0, fmt.Errorf("failed to add startup entry '%s' to any preferred location: %w", entry.Name, errors.Join(errs...))
+}
+
+// RemoveStartupEntry removes an application from Windows startup registry
+// together with its metadata. A pinned entry is only removed when WithForce is
+// passed, otherwise the error wraps ErrEntryPinned. Without WithForce, metadata
+// that cannot be read fails the removal as well
+func RemoveStartupEntry(entryName string, registryType StartupRegistryType, opts ...RemoveOption) error {
+	return RemoveStartupEntryWithView(entryName, registryType, DefaultView, opts...)
+}
+
+// RemoveStartupEntryWithView removes an entry through an explicit registry view,
+// the counterpart of AddStartupEntryWithView
+func RemoveStartupEntryWithView(entryName string, registryType StartupRegistryType, view RegistryView, opts ...RemoveOption) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if err := requireElevation(registryType); err != nil {
+		return err
+	}
+
+	options := removeOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Pinned entries stay unless the caller insists
+	if !options.force {
+		// Metadata that cannot be read may hold a pin, so it stops the removal too
+		metadata, err := GetEntryMetadataTyped(entryName, registryType)
+		if err != nil && !errors.Is(err, ErrNoMetadata) {
+			return err
+		}
+		if err == nil && metadata.Pinned {
+			return fmt.Errorf("startup entry '%s' in %s: %w", entryName, registryType, ErrEntryPinned)
+		}
+	}
+
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Attempt to open the registry key with write access, and read access for the journal
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE|registry.SET_VALUE|uint32(view))
+	if err != nil {
+		return fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	// Open the journal before anything changes so a required journal can stop the removal
+	journal, err := openJournal()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	var before string
+	if journal != nil {
+		before, _, _ = k.GetStringValue(entryName)
+	}
+
+	// Attempt to delete the value
+	err = k.DeleteValue(entryName)
+	if err != nil {
+		// Check if the error indicates the value doesn't exist
+		if errors.Is(err, registry.ErrNotExist) {
+			return fmt.Errorf("startup entry '%s' not found in %s: %w", entryName, keyPath, ErrEntryNotFound)
+		}
+		return fmt.Errorf("failed to delete registry value: %w", classifyRegistryError(err, ErrEntryNotFound))
+	}
+
+	if err := journal.record(JournalEntry{Operation: JournalRemove, Location: registryType, Name: entryName, Before: before}); err != nil {
+		return err
+	}
+
+	// Clean up a generated launcher script that only this entry used, then the
+	// metadata, so a later entry of the same name does not inherit a pin or owner
+	if !options.keepLauncher {
+		if err := removeLauncher(entryName, registryType); err != nil {
+			return err
+		}
+	}
+	return deleteEntryMetadata(entryName, registryType)
+}
+
+// SafeRemoveStartupEntry provides a comprehensive removal method. Pinned
+// entries are left in place unless WithForce is passed and reported with
+// ErrEntryPinned, even when the entry was removed from other locations. The
+// same goes for a location that holds the entry but refuses the removal, which
+// is reported with ErrAccessDenied. An entry found nowhere gives ErrEntryNotFound
+func SafeRemoveStartupEntry(entryName string, opts ...RemoveOption) error {
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var lastErr, pinnedErr, deniedErr error
+	var removedFromAny bool
+
+	// Try to remove from all possible locations
+	for _, registryType := range registryTypes {
+		err := RemoveStartupEntry(entryName, registryType, opts...)
+		switch {
+		case err == nil:
+			removedFromAny = true
+		case errors.Is(err, ErrEntryPinned):
+			pinnedErr = err
+		case errors.Is(err, ErrEntryNotFound), errors.Is(err, ErrKeyNotFound):
+			// Not in this location
+		case errors.Is(err, ErrAccessDenied):
+			// Write access is refused for locations without the entry as well
+			if present, existsErr := EntryExists(entryName, registryType); existsErr != nil || present {
+				deniedErr = fmt.Errorf("%s: %w", registryType, err)
+			}
+		default:
+			lastErr = err
+		}
+	}
+
+	// A copy that stayed behind is always reported
+	if pinnedErr != nil {
+		return pinnedErr
+	}
+	if deniedErr != nil {
+		return fmt.Errorf("failed to remove startup entry '%s': %w", entryName, deniedErr)
+	}
+	if !removedFromAny {
+		if lastErr == nil {
+			return fmt.Errorf("startup entry '%s' not found in any location: %w", entryName, ErrEntryNotFound)
+		}
+		return fmt.Errorf("failed to remove startup entry '%s' from any location: %w", entryName, lastErr)
+	}
+
+	return nil
+}
+
+// EnsureAbsent removes an entry from every known location it is present in.
+// Unlike SafeRemoveStartupEntry, an entry that is already gone is not an error
+func EnsureAbsent(name string) (wasPresent bool, err error) {
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	var errs []error
+
+	// Only touch the locations that actually hold the entry
+	for _, registryType := range registryTypes {
+		present, err := EntryExists(name, registryType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !present {
+			continue
+		}
+
+		wasPresent = true
+		if err := RemoveStartupEntry(name, registryType); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return wasPresent, errors.Join(errs...)
+}
+
+// classifyRegistryError tags a registry failure with a sentinel callers can test
+// with errors.Is: notFound for a missing key or value and ErrAccessDenied for
+// ERROR_ACCESS_DENIED. The original error stays in the chain
+func classifyRegistryError(err, notFound error) error {
+	switch {
+	case errors.Is(err, registry.ErrNotExist):
+		return fmt.Errorf("%w: %w", notFound, err)
+	case errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		return fmt.Errorf("%w: %w", ErrAccessDenied, err)
+	default:
+		return err
+	}
+}
+
+// EntryExists reports whether a value with the given name is present in a startup
+// location without reading the whole key. A missing key is treated the same as a
+// missing value and is not an error
+func EntryExists(name string, registryType StartupRegistryType) (bool, error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key with read access
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer k.Close()
+
+	// Query the value size only, the data itself is not needed
+	_, _, err = k.GetValue(name, nil)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to query registry value: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetStartupEntry reads a single entry from a startup location and splits its
+// command as ParseStartupEntry does. found is false, with a nil error, when the
+// location has no such value. REG_EXPAND_SZ commands are returned unexpanded
+func GetStartupEntry(name string, registryType StartupRegistryType) (entry StartupEntry, found bool, err error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key with read access
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return StartupEntry{}, false, nil
+		}
+		return StartupEntry{}, false, fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer k.Close()
+
+	command, _, err := k.GetStringValue(name)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return StartupEntry{}, false, nil
+		}
+		return StartupEntry{}, false, fmt.Errorf("failed to read startup entry '%s' in %s: %w", name, registryType, err)
+	}
+
+	return ParseStartupEntry(name, command), true, nil
+}
+
+// currentCommand returns the command of an entry read through view, or "" when it
+// is absent or unreadable
+func currentCommand(name string, registryType StartupRegistryType, view RegistryView) string {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE|uint32(view))
+	if err != nil {
+		return ""
+	}
+	defer k.Close()
+
+	command, _, _ := k.GetStringValue(name)
+	return command
+}
+
+// ListStartupEntries retrieves startup entries from a specific registry location.
+// A command that is a single quoted path is returned without its quotes, so an
+// entry added for C:\Program Files\App\app.exe reads back as that path
+func ListStartupEntries(registryType StartupRegistryType) (map[string]string, error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key with read access
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	// Get all value names
+	valueNames, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value names: %w", err)
+	}
+
+	// Create a map to store startup entries
+	entries := make(map[string]string)
+
+	// Read each value
+	for _, name := range valueNames {
+		value, _, err := k.GetStringValue(name)
+		if err == nil {
+			entries[name] = unquoteCommand(value)
+		}
+	}
+
+	return entries, nil
+}
+
+// ListStartupEntriesByType retrieves only the startup entries stored with the given
+// registry value type (for example registry.EXPAND_SZ) from a specific location
+func ListStartupEntriesByType(registryType StartupRegistryType, valueType uint32) (map[string]string, error) {
+	// Get registry path and root key
+	keyPath, rootKey := getRegistryPath(registryType)
+
+	// Open the registry key with read access
+	k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry key: %w", classifyRegistryError(err, ErrKeyNotFound))
+	}
+	defer k.Close()
+
+	// Get all value names
+	valueNames, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value names: %w", err)
+	}
+
+	// Create a map to store matching startup entries
+	entries := make(map[string]string)
+
+	// Query the type of each value and keep the ones that match
+	for _, name := range valueNames {
+		_, valType, err := k.GetValue(name, nil)
+		if err != nil || valType != valueType {
+			continue
+		}
+
+		value, err := readValueString(k, name, valType)
+		if err == nil {
+			entries[name] = value
+		}
+	}
+
+	return entries, nil
+}
+
+// readValueString reads a value of any type and renders its data as a string.
+// String types are returned verbatim (REG_EXPAND_SZ is not expanded), integers
+// in decimal, REG_MULTI_SZ joined with spaces and anything else as hex
+func readValueString(k registry.Key, name string, valType uint32) (string, error) {
+	switch valType {
+	case registry.SZ, registry.EXPAND_SZ:
+		value, _, err := k.GetStringValue(name)
+		return value, err
+	case registry.DWORD, registry.QWORD:
+		value, _, err := k.GetIntegerValue(name)
+		return strconv.FormatUint(value, 10), err
+	case registry.MULTI_SZ:
+		values, _, err := k.GetStringsValue(name)
+		return strings.Join(values, " "), err
+	default:
+		n, _, err := k.GetValue(name, nil)
+		if err != nil {
+			return "", err
+		}
+		buf := make([]byte, n)
+		n, _, err = k.GetValue(name, buf)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(buf[:n]), nil
+	}
+}
+
+// ListAllStartupEntries retrieves startup entries from all known locations
+func ListAllStartupEntries() (map[StartupRegistryType]map[string]string, error) {
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	// Map to store all startup entries
+	allEntries := make(map[StartupRegistryType]map[string]string)
+
+	// Retrieve entries from each location
+	for _, registryType := range registryTypes {
+		entries, err := ListStartupEntries(registryType)
+		if err == nil && len(entries) > 0 {
+			allEntries[registryType] = entries
+		}
+	}
+
+	return allEntries, nil
+}
+
+// SummarizeStartupEntries retrieves only the value names of every known location,
+// without reading their data. A location whose key is missing yields an empty slice
+func SummarizeStartupEntries() (map[StartupRegistryType][]string, error) {
+	// List of registry types to check
+	registryTypes := []StartupRegistryType{
+		CurrentUserRun,
+		CurrentUserRunOnce,
+		AllUsersRun,
+		AllUsersRunOnce,
+	}
+
+	summary := make(map[StartupRegistryType][]string)
+
+	for _, registryType := range registryTypes {
+		// Get registry path and root key
+		keyPath, rootKey := getRegistryPath(registryType)
+		summary[registryType] = []string{}
+
+		k, err := registry.OpenKey(rootKey, keyPath, registry.QUERY_VALUE)
+		if err != nil {
+			if errors.Is(err, registry.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to open registry key: %w", err)
+		}
+
+		valueNames, err := k.ReadValueNames(0)
+		k.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value names: %w", err)
+		}
+		sort.Strings(valueNames)
+		summary[registryType] = valueNames
+	}
+
+	return summary, nil
+}